@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"mock-server/internal/config"
+	"mock-server/internal/recorder"
+	"mock-server/internal/server"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultRecordConfig = "recorded.json"
+
+// parse parses the flags of the command and loads the options, it returns
+// the exit code when the command has to stop
+func parse(flags *flag.FlagSet, o *options, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, false
+		}
+		return 2, false
+	}
+
+	if err := o.load(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1, false
+	}
+
+	return 0, true
+}
+
+// newLogger creates the logger of the level of the options
+func newLogger() logger.Logger {
+	mockLogger, err := logger.CreateNewLogger()
+	if err != nil {
+		log.Fatal("Error initializing logger")
+	}
+	mockLogger.Info("logger successfully initialized")
+	return mockLogger
+}
+
+// serve starts the mock server of the config until it is stopped
+func serve(args []string) int {
+	flags, o := newFlagSet("serve", "Start the mock server.")
+	if code, ok := parse(flags, o, args); !ok {
+		return code
+	}
+
+	mockLogger := newLogger()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		mockLogger.Error("Error loading config", err)
+		return 1
+	}
+	mockLogger.InfoW("config successfully loaded", map[string]any{"config": cfg})
+
+	return run(mockLogger, cfg)
+}
+
+// record starts the mock server forwarding the requests without an endpoint
+// to the upstream and recording them
+func record(args []string) int {
+	flags, o := newFlagSet("record", "Start the mock server proxying the requests without an endpoint to the upstream\nand record them. Without a config file only the upstream is served.")
+	upstream := o.add(flags, "upstream", "RECORD_UPSTREAM", "", "base `url` of the API to record")
+	outputDir := o.add(flags, "output-dir", "RECORD_OUTPUT_DIR", "", "`directory` of the recorded response files, default recordings")
+	recordConfig := o.add(flags, "record-config", "RECORD_CONFIG_FILE", defaultRecordConfig, "config `file` the recorded endpoints are written to")
+	if code, ok := parse(flags, o, args); !ok {
+		return code
+	}
+
+	mockLogger := newLogger()
+
+	cfg := &config.APIConfig{}
+	if os.Getenv("CONFIG_FILE_PATH") != "" {
+		var err error
+		if cfg, err = config.LoadConfig(); err != nil {
+			mockLogger.Error("Error loading config", err)
+			return 1
+		}
+	}
+
+	cfg.Record = &config.Record{Upstream: *upstream, OutputDir: *outputDir, ConfigFile: *recordConfig}
+	if err := config.Validate(cfg); err != nil {
+		mockLogger.Error("Error loading config", err)
+		return 1
+	}
+	mockLogger.InfoW("config successfully loaded", map[string]any{"config": cfg})
+
+	return run(mockLogger, cfg)
+}
+
+// run serves the config until a shutdown signal
+func run(mockLogger logger.Logger, cfg *config.APIConfig) int {
+	ctx, stop := signal.NotifyContext(context.Background(),
+		os.Interrupt,    // Ctrl+C
+		syscall.SIGTERM, // Kubernetes/Docker stop
+		syscall.SIGQUIT) // Graceful shutdown
+	defer stop()
+
+	if err := server.CreateServer(ctx, cfg); err != nil {
+		mockLogger.Error("Server error", err)
+		return 1
+	}
+
+	<-ctx.Done()
+	mockLogger.Info("Received shutdown signal")
+	return 0
+}
+
+// validate checks the config and builds its endpoints, which loads the files
+// they reference
+func validate(args []string) int {
+	flags, o := newFlagSet("validate", "Check the config file and build its endpoints, which reads the files they reference.")
+	if code, ok := parse(flags, o, args); !ok {
+		return code
+	}
+
+	newLogger()
+	gin.SetMode(gin.ReleaseMode)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s is invalid: %v\n", os.Getenv("CONFIG_FILE_PATH"), err)
+		return 1
+	}
+
+	r, err := server.Routes(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s is invalid: %v\n", os.Getenv("CONFIG_FILE_PATH"), err)
+		return 1
+	}
+
+	fmt.Printf("%s is valid: %d endpoints, %d virtual hosts, %d routes\n", os.Getenv("CONFIG_FILE_PATH"), len(cfg.Endpoints), len(cfg.VirtualHosts), len(r))
+	return 0
+}
+
+// routes lists the routes of the config
+func routes(args []string) int {
+	flags, o := newFlagSet("routes", "List the routes the config serves, by port and host.")
+	if code, ok := parse(flags, o, args); !ok {
+		return code
+	}
+
+	newLogger()
+	gin.SetMode(gin.ReleaseMode)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	r, err := server.Routes(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PORT\tHOSTS\tMETHOD\tPATH")
+	for _, route := range r {
+		hosts := "*"
+		if len(route.Hosts) > 0 {
+			hosts = strings.Join(route.Hosts, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", route.Port, hosts, route.Method, route.Path)
+	}
+	if err := w.Flush(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// importHAR records the requests of a HAR file as endpoints and response files
+func importHAR(args []string) int {
+	flags, o := newFlagSet("import", "Import the requests of a HAR file, e.g. exported by the browser developer tools,\nas endpoints and response files like the record mode.\n\nUsage: mock-server import [flags] <file.har>")
+	outputDir := o.add(flags, "output-dir", "RECORD_OUTPUT_DIR", "", "`directory` of the response files, default recordings")
+	recordConfig := o.add(flags, "record-config", "RECORD_CONFIG_FILE", defaultRecordConfig, "config `file` the endpoints are added to")
+	prefix := flags.String("prefix", "", "import only the requests whose URL starts with this `url`")
+	if code, ok := parse(flags, o, args); !ok {
+		return code
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	newLogger()
+
+	cfg := &config.Record{OutputDir: *outputDir, ConfigFile: *recordConfig}
+	imported, err := recorder.ImportHAR(cfg, flags.Arg(0), *prefix)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("imported %d requests into %s\n", imported, *recordConfig)
+	return 0
+}
@@ -1,45 +1,51 @@
 package main
 
 import (
-	"context"
-	"log"
+	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
-
-	"mock-server/internal/config"
-	"mock-server/internal/server"
-	"mock-server/pkg/logger"
-
 )
 
+const usage = `Usage: mock-server <command> [flags]
+
+Commands:
+  serve     Start the mock server (default)
+  validate  Check the config file and the files it references
+  routes    List the routes the config serves
+  record    Start the mock server proxying unknown requests to an upstream and record them
+  import    Import the requests of a HAR file as endpoints and response files
+
+Run 'mock-server <command> -h' for the flags of a command. The flags fall back
+to the environment variables, which can be set in a .env file.
+`
+
+// commands are the subcommands by name
+var commands = map[string]func(args []string) int{
+	"serve":    serve,
+	"validate": validate,
+	"routes":   routes,
+	"record":   record,
+	"import":   importHAR,
+}
+
 func main() {
+	args := os.Args[1:]
 
-	mockLogger, err := logger.CreateNewLogger()
-	if err != nil {
-		log.Fatal("Error initializing logger")
+	// Without a command, or with flags only, the server is started
+	name := "serve"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		name, args = args[0], args[1:]
 	}
-	mockLogger.Info("logger successfully initialized")
 
-	config, err := config.LoadConfig()
-	if err != nil {
-		mockLogger.Error("Error loading config", err)
+	if name == "help" {
+		fmt.Print(usage)
 		return
 	}
-	mockLogger.InfoW("config successfully loaded", map[string]any{"config": config})
-
-	
-	ctx, stop := signal.NotifyContext(context.Background(),
-		os.Interrupt,    // Ctrl+C
-		syscall.SIGTERM, // Kubernetes/Docker stop
-		syscall.SIGQUIT) // Graceful shutdown
-	defer stop()
-
-	if err := server.CreateServer(ctx, config); err != nil {
-		mockLogger.Error("Server error", err)
-		os.Exit(1)
+
+	command, found := commands[name]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
 	}
 
-	<-ctx.Done()
-	mockLogger.Info("Received shutdown signal")
+	os.Exit(command(args))
 }
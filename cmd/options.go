@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	defaultEnvFile = ".env"
+	defaultPort    = "8080"
+)
+
+// option is a flag falling back to its environment variable, then to its default
+type option struct {
+	value    *string
+	env      string
+	fallback string
+}
+
+// options are the flags shared by the commands
+type options struct {
+	envFile string
+	values  []option
+}
+
+// newFlagSet creates the flag set of the command with the shared flags
+func newFlagSet(name, description string) (*flag.FlagSet, *options) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: mock-server %s [flags]\n\n%s\n\nFlags:\n", name, description)
+		flags.PrintDefaults()
+	}
+
+	o := &options{}
+	flags.StringVar(&o.envFile, "env-file", defaultEnvFile, "`file` of environment variables to load, existing variables are kept")
+	o.add(flags, "config", "CONFIG_FILE_PATH", "", "config `file`")
+	o.add(flags, "port", "PORT", defaultPort, "port of the HTTP server")
+	o.add(flags, "host", "HOST", "", "address the HTTP servers listen on, default all")
+	o.add(flags, "log-level", "LEVEL", "", "`level` of the logs, PRODUCTION or DEVELOPMENT")
+
+	return flags, o
+}
+
+// add registers a flag falling back to the environment variable
+func (o *options) add(flags *flag.FlagSet, name, env, fallback, usage string) *string {
+	usage += " (env " + env + ")"
+	if fallback != "" {
+		usage += ", default " + fallback
+	}
+
+	value := flags.String(name, "", usage)
+	o.values = append(o.values, option{value: value, env: env, fallback: fallback})
+	return value
+}
+
+// load loads the env file, a missing default one is not an error, then takes
+// each value from the command line, its environment variable or its default,
+// and sets the environment variables the server reads
+func (o *options) load() error {
+	if err := godotenv.Load(o.envFile); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) || o.envFile != defaultEnvFile {
+			return fmt.Errorf("failed to load env file %s: %w", o.envFile, err)
+		}
+	}
+
+	for _, opt := range o.values {
+		if *opt.value == "" {
+			*opt.value = os.Getenv(opt.env)
+		}
+		if *opt.value == "" {
+			*opt.value = opt.fallback
+		}
+		if err := os.Setenv(opt.env, *opt.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
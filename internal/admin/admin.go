@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"mock-server/internal/state"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// BasePath is the path all the admin operations are served under
+	BasePath = "/__admin"
+)
+
+// RegisterRoutes registers the admin operations
+func RegisterRoutes(engine *gin.Engine, registry *state.Registry) {
+	group := engine.Group(BasePath)
+
+	group.POST("/snapshot", snapshotHandler(registry))
+	group.POST("/restore", restoreHandler(registry))
+}
+
+// snapshotHandler returns the state of the mock server, and writes it to the
+// persistence file when it is configured
+func snapshotHandler(registry *state.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		snapshot, err := registry.Save()
+		if err != nil {
+			logger.GetLogger().Error("failed to snapshot mock state", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to snapshot mock state"})
+			return
+		}
+
+		c.JSON(http.StatusOK, snapshot)
+	}
+}
+
+// restoreHandler restores the state of the mock server from the snapshot in the
+// request body, or from the persistence file when the body is empty
+func restoreHandler(registry *state.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var mockLogger = logger.GetLogger()
+
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+
+		if len(data) == 0 {
+			if err := registry.Load(); err != nil {
+				mockLogger.Error("failed to restore mock state", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore mock state"})
+				return
+			}
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		var snapshot state.Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid snapshot"})
+			return
+		}
+
+		if err := registry.Restore(snapshot); err != nil {
+			if errors.Is(err, state.ErrInvalidSnapshot) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			mockLogger.Error("failed to restore mock state", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore mock state"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
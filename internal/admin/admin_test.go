@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mock-server/internal/state"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// counter is a stateful item holding a number
+type counter struct {
+	value int
+}
+
+func (c *counter) Snapshot() (json.RawMessage, error) {
+	return json.Marshal(c.value)
+}
+
+func (c *counter) Decode(data json.RawMessage) (func(), error) {
+	var value int
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return func() { c.value = value }, nil
+}
+
+func TestRestoreHandler(t *testing.T) {
+	item := &counter{value: 1}
+	registry := state.NewRegistry("")
+	registry.Register("resource:/items", item)
+
+	engine := gin.New()
+	RegisterRoutes(engine, registry)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		value  int
+	}{
+		{"invalid json", `{`, http.StatusBadRequest, 1},
+		{"unknown item", `{"resource:/items":5,"resource:/itemz":6}`, http.StatusBadRequest, 1},
+		{"item not decoded", `{"resource:/items":"x"}`, http.StatusBadRequest, 1},
+		{"restored", `{"resource:/items":5}`, http.StatusNoContent, 5},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, BasePath+"/restore", bytes.NewBufferString(tt.body)))
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+		}
+		if item.value != tt.value {
+			t.Errorf("%s: value = %d, want %d", tt.name, item.value, tt.value)
+		}
+	}
+}
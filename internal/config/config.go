@@ -2,38 +2,392 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"mock-server/pkg/logger"
 )
 
 type APIConfig struct {
+	Endpoints    []Endpoint    `json:"endpoints"`
+	Record       *Record       `json:"record,omitempty"`
+	Persistence  *Persistence  `json:"persistence,omitempty"`
+	GRPC         *GRPC         `json:"grpc,omitempty"`
+	Webhooks     *Webhooks     `json:"webhooks,omitempty"`
+	TLS          *TLS          `json:"tls,omitempty"`
+	Protocols    *Protocols    `json:"protocols,omitempty"`
+	Timeouts     *Timeouts     `json:"timeouts,omitempty"`
+	VirtualHosts []VirtualHost `json:"virtualHosts,omitempty"`
+}
+
+// VirtualHost mocks a vendor next to the main endpoints, listening on its own
+// Port or serving the requests whose Host header is one of Hosts. Its Endpoints
+// are served under BasePath and require Auth when it is set.
+type VirtualHost struct {
+	Name      string     `json:"name"`
+	Port      string     `json:"port,omitempty"`
+	Hosts     []string   `json:"hosts,omitempty"`
+	BasePath  string     `json:"basePath,omitempty"`
+	Auth      *Auth      `json:"auth,omitempty"`
 	Endpoints []Endpoint `json:"endpoints"`
 }
 
+// Auth checks the credentials of the requests. The bearer type accepts the
+// Tokens in the Authorization header, apiKey the Tokens in the Header, default
+// X-API-Key, or the Query parameter, and basic the Username and Password.
+type Auth struct {
+	Type     string   `json:"type"`
+	Tokens   []string `json:"tokens,omitempty"`
+	Header   string   `json:"header,omitempty"`
+	Query    string   `json:"query,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+}
+
+// Protocols selects the HTTP versions the server speaks. HTTP/2 is negotiated
+// over TLS by default; H2C serves HTTP/2 over cleartext to the clients with prior
+// knowledge, and HTTP1Only turns HTTP/2 off to reproduce downgrades.
+type Protocols struct {
+	H2C       bool `json:"h2c,omitempty"`
+	HTTP1Only bool `json:"http1Only,omitempty"`
+}
+
+// Timeouts of the HTTP listeners, as durations like 30s. Read limits reading
+// a request, Write writing its response, streams excepted, and Idle the wait of
+// a keep-alive connection for the next request; 0s turns a timeout off.
+type Timeouts struct {
+	Read  string `json:"read,omitempty"`
+	Write string `json:"write,omitempty"`
+	Idle  string `json:"idle,omitempty"`
+}
+
+// TLS serves HTTPS with the certificate CertFile and its key KeyFile. Without
+// them a self-signed CA is generated, issuing the server certificate for Hosts;
+// its certificate, and a client certificate, are written to OutputDir. ClientAuth
+// optional or require verifies the client certificates against ClientCAFile,
+// or the generated CA.
+type TLS struct {
+	CertFile     string   `json:"certFile,omitempty"`
+	KeyFile      string   `json:"keyFile,omitempty"`
+	Hosts        []string `json:"hosts,omitempty"`
+	OutputDir    string   `json:"outputDir,omitempty"`
+	ClientAuth   string   `json:"clientAuth,omitempty"`
+	ClientCAFile string   `json:"clientCAFile,omitempty"`
+}
+
+// Webhooks lets the clients subscribe callback URLs on SubscriptionPath and
+// POSTs the Events to the subscribed URLs, every Schedule of an event or when
+// triggered on the admin API. The bodies are signed with an HMAC of Secret in
+// SignatureHeader; failed deliveries are retried MaxRetries times with a
+// doubling RetryBackoff, and a subscription failing DisableAfter deliveries in
+// a row is deactivated.
+type Webhooks struct {
+	SubscriptionPath   string         `json:"subscriptionPath,omitempty"`
+	Secret             string         `json:"secret,omitempty"`
+	SignatureHeader    string         `json:"signatureHeader,omitempty"`
+	SignatureAlgorithm string         `json:"signatureAlgorithm,omitempty"`
+	TimestampHeader    string         `json:"timestampHeader,omitempty"`
+	MaxRetries         int            `json:"maxRetries,omitempty"`
+	RetryBackoff       string         `json:"retryBackoff,omitempty"`
+	Timeout            string         `json:"timeout,omitempty"`
+	DisableAfter       int            `json:"disableAfter,omitempty"`
+	Events             []WebhookEvent `json:"events"`
+}
+
+// WebhookEvent is an event type whose payload is the JSON file ResponseObjFilePath
+type WebhookEvent struct {
+	Name                string            `json:"name"`
+	ResponseObjFilePath string            `json:"responseObjFilePath,omitempty"`
+	Schedule            string            `json:"schedule,omitempty"`
+	Headers             map[string]string `json:"headers,omitempty"`
+}
+
+// GRPC serves the services of the .proto files ProtoFiles, found in ImportPaths,
+// or of the FileDescriptorSet file DescriptorSet on Port next to the HTTP server.
+// The methods are keyed by their full name, e.g. shop.v1.OrderService/ListOrders.
+type GRPC struct {
+	Port          string                `json:"port"`
+	ProtoFiles    []string              `json:"protoFiles,omitempty"`
+	ImportPaths   []string              `json:"importPaths,omitempty"`
+	DescriptorSet string                `json:"descriptorSet,omitempty"`
+	Reflection    bool                  `json:"reflection,omitempty"`
+	Methods       map[string]GRPCMethod `json:"methods"`
+}
+
+// GRPCMethod answers a unary or server-streaming method with the JSON fixture
+// ResponseObjFilePath, converted to the response message with protojson.
+// Responses with a next_page_token field are paginated per AIP-158 over the
+// repeated field ResponseField. Server-streaming methods send the records of a
+// top level array one message at a time, StreamDelay apart. A Status other than
+// OK, e.g. NOT_FOUND, is returned as the error of the method.
+type GRPCMethod struct {
+	ResponseObjFilePath string     `json:"responseObjFilePath"`
+	ResponseField       string     `json:"responseField,omitempty"`
+	Pagination          Pagination `json:"pagination,omitempty"`
+	StreamDelay         string     `json:"streamDelay,omitempty"`
+	Status              string     `json:"status,omitempty"`
+	Message             string     `json:"message,omitempty"`
+}
+
+// Persistence writes the mock state (e.g. resource collections) to File
+// every Interval and on shutdown, and restores it on start
+type Persistence struct {
+	File     string `json:"file"`
+	Interval string `json:"interval,omitempty"`
+}
+
+// Record enables the record-and-replay proxy mode. Requests that do not
+// match a configured endpoint are forwarded to Upstream, and the captured
+// responses are written as response files and endpoints into ConfigFile so
+// that they can be replayed later without the upstream.
+type Record struct {
+	Upstream   string `json:"upstream"`
+	OutputDir  string `json:"outputDir"`
+	ConfigFile string `json:"configFile"`
+}
+
 type Endpoint struct {
+	Type                string         `json:"type,omitempty"`
 	Path                string         `json:"path"`
 	Method              string         `json:"method"`
 	Headers             map[string]any `json:"headers"`
 	QueryParams         map[string]any `json:"queryParams"`
 	RequestBody         map[string]any `json:"requestBody"`
 	RateLimit           int            `json:"rateLimit"`
-	Pagination          pagination     `json:"pagination"`
+	Pagination          Pagination     `json:"pagination"`
 	ResponseObjFilePath string         `json:"responseObjFilePath"`
 	ResponseField       string         `json:"responseField,omitempty"`
+	StatusCode          int            `json:"statusCode,omitempty"`
+	ResponseHeaders     map[string]any `json:"responseHeaders,omitempty"`
+	Matchers            []Matcher      `json:"matchers,omitempty"`
+	Priority            int            `json:"priority,omitempty"`
+	Lookup              *Lookup        `json:"lookup,omitempty"`
+	Timeline            *Timeline      `json:"timeline,omitempty"`
+	Mutations           []Mutation     `json:"mutations,omitempty"`
+	Query               *Query         `json:"query,omitempty"`
+	Metadata            *Metadata      `json:"metadata,omitempty"`
+	Export              *Export        `json:"export,omitempty"`
+	GraphQL             *GraphQL       `json:"graphql,omitempty"`
+	SSE                 *SSE           `json:"sse,omitempty"`
+	WebSocket           *WebSocket     `json:"websocket,omitempty"`
+	Job                 *Job           `json:"job,omitempty"`
+}
+
+// GraphQL serves a GraphQL API with the schema of the SDL file SchemaFile.
+// The fields are resolved from the response files of Resolvers, keyed by
+// Type.field (e.g. Query.users); the other fields are read from the value
+// of their parent.
+type GraphQL struct {
+	SchemaFile string              `json:"schemaFile"`
+	Resolvers  map[string]Resolver `json:"resolvers"`
+}
+
+// Resolver resolves a GraphQL field from a response file. List and Relay
+// connection fields serve the dataset of the file like a paginated endpoint,
+// Lookup serves the record whose Field equals the argument Param.
+type Resolver struct {
+	ResponseObjFilePath string     `json:"responseObjFilePath"`
+	ResponseField       string     `json:"responseField,omitempty"`
+	Pagination          Pagination `json:"pagination,omitempty"`
+	Lookup              *Lookup    `json:"lookup,omitempty"`
+}
+
+// Export renders the dataset of the endpoint as a CSV or NDJSON file instead
+// of the response object. Chunking splits the file into chunks of ChunkSize
+// records fetched by a chunk token, or serves byte ranges of the file; Stream
+// flushes ChunkSize records at a time, StreamDelay apart.
+type Export struct {
+	Format      string         `json:"format"`
+	Columns     []ExportColumn `json:"columns,omitempty"`
+	Delimiter   string         `json:"delimiter,omitempty"`
+	NoHeader    bool           `json:"noHeader,omitempty"`
+	FileName    string         `json:"fileName,omitempty"`
+	Chunking    string         `json:"chunking,omitempty"`
+	ChunkSize   int            `json:"chunkSize,omitempty"`
+	TokenKey    string         `json:"tokenKey,omitempty"`
+	TokenHeader string         `json:"tokenHeader,omitempty"`
+	Stream      bool           `json:"stream,omitempty"`
+	StreamDelay string         `json:"streamDelay,omitempty"`
+}
+
+// SSE streams the records of the dataset of an sse endpoint as Server-Sent
+// Events, Interval apart. The id of an event is the record field IDField, and
+// a client reconnecting with Last-Event-ID resumes after that record. The
+// stream is disconnected after DisconnectAfter events, or at random with
+// DisconnectProbability per event, closing the response or aborting the
+// connection as DisconnectMode says.
+type SSE struct {
+	Interval              string  `json:"interval,omitempty"`
+	Event                 string  `json:"event,omitempty"`
+	EventField            string  `json:"eventField,omitempty"`
+	IDField               string  `json:"idField,omitempty"`
+	Retry                 int     `json:"retry,omitempty"`
+	KeepOpen              bool    `json:"keepOpen,omitempty"`
+	Heartbeat             string  `json:"heartbeat,omitempty"`
+	DisconnectAfter       int     `json:"disconnectAfter,omitempty"`
+	DisconnectProbability float64 `json:"disconnectProbability,omitempty"`
+	DisconnectMode        string  `json:"disconnectMode,omitempty"`
+}
+
+// Job simulates an asynchronous job, e.g. an export. A request to the endpoint
+// starts a job that is pending for PendingDuration, then running for
+// RunningDuration before it completes, or fails with FailProbability. The job
+// is reported on StatusPath and the dataset of the endpoint is served on
+// ResultPath once it is complete. Statuses renames the reported statuses.
+type Job struct {
+	StatusPath      string            `json:"statusPath,omitempty"`
+	ResultPath      string            `json:"resultPath,omitempty"`
+	PendingDuration string            `json:"pendingDuration,omitempty"`
+	RunningDuration string            `json:"runningDuration,omitempty"`
+	FailProbability float64           `json:"failProbability,omitempty"`
+	FailureMessage  string            `json:"failureMessage,omitempty"`
+	Statuses        map[string]string `json:"statuses,omitempty"`
 }
 
-type pagination struct {
+// WebSocket serves a websocket endpoint. Messages are pushed in order once the
+// connection is open; inbound messages are answered by the first matching rule,
+// or echoed back with Echo. The server pings every PingInterval and drops the
+// clients that do not answer within PongTimeout. Close ends the connection
+// after the pushed messages, and the disconnect options inject failures like
+// for SSE endpoints.
+type WebSocket struct {
+	Messages              []WebSocketMessage `json:"messages,omitempty"`
+	Echo                  bool               `json:"echo,omitempty"`
+	Rules                 []WebSocketRule    `json:"rules,omitempty"`
+	Subprotocols          []string           `json:"subprotocols,omitempty"`
+	PingInterval          string             `json:"pingInterval,omitempty"`
+	PongTimeout           string             `json:"pongTimeout,omitempty"`
+	Close                 *WebSocketClose    `json:"close,omitempty"`
+	DisconnectAfter       int                `json:"disconnectAfter,omitempty"`
+	DisconnectProbability float64            `json:"disconnectProbability,omitempty"`
+	DisconnectMode        string             `json:"disconnectMode,omitempty"`
+}
+
+// WebSocketMessage is a message sent Delay after the previous one. Data is sent
+// as it is when it is a string and as JSON otherwise; ResponseObjFilePath sends
+// the content of the file instead.
+type WebSocketMessage struct {
+	Delay               string `json:"delay,omitempty"`
+	Data                any    `json:"data,omitempty"`
+	ResponseObjFilePath string `json:"responseObjFilePath,omitempty"`
+	Binary              bool   `json:"binary,omitempty"`
+}
+
+// WebSocketRule answers the inbound messages matching all its body Matchers
+// with Responses, then closes the connection when Close is set
+type WebSocketRule struct {
+	Matchers  []Matcher          `json:"matchers,omitempty"`
+	Responses []WebSocketMessage `json:"responses,omitempty"`
+	Close     *WebSocketClose    `json:"close,omitempty"`
+}
+
+// WebSocketClose closes the connection with the close Code and Reason, Delay
+// after the last message
+type WebSocketClose struct {
+	Code   int    `json:"code"`
+	Reason string `json:"reason,omitempty"`
+	Delay  string `json:"delay,omitempty"`
+}
+
+// ExportColumn is a CSV column named Name holding the record value at the
+// JSON path Field, which defaults to Name
+type ExportColumn struct {
+	Name  string `json:"name"`
+	Field string `json:"field,omitempty"`
+}
+
+// Metadata reports the pagination facts (currentPage, pageSize, totalPages,
+// totalRecords, offset, hasNext, hasPrevious, nextCursor, prevCursor) at
+// JSON paths of the response body and in response headers
+type Metadata struct {
+	Body    map[string]string `json:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Query filters, sorts and selects the fields of the endpoint records by the
+// query parameters of the request before they are paginated
+type Query struct {
+	Filter       bool     `json:"filter,omitempty"`
+	FilterFields []string `json:"filterFields,omitempty"`
+	SortKey      string   `json:"sortKey,omitempty"`
+	FieldsKey    string   `json:"fieldsKey,omitempty"`
+}
+
+// Mutation changes the dataset of the endpoint after the AfterRequest-th
+// request, and after every Every requests when Every is set. Action is one of
+// insert, delete or reorder and Position one of head, tail or random, or
+// reverse for reorder.
+type Mutation struct {
+	AfterRequest int    `json:"afterRequest"`
+	Every        int    `json:"every,omitempty"`
+	Action       string `json:"action"`
+	Count        int    `json:"count,omitempty"`
+	Position     string `json:"position,omitempty"`
+}
+
+// Timeline stamps the records of the endpoint with generated timestamps and
+// filters them by the timestamp filter parameters of the request
+type Timeline struct {
+	Field   string            `json:"field,omitempty"`
+	Start   string            `json:"start,omitempty"`
+	Step    string            `json:"step,omitempty"`
+	Filters map[string]string `json:"filters,omitempty"`
+	Advance *Advance          `json:"advance,omitempty"`
+}
+
+// Advance adds Records new records to the timeline Every interval
+type Advance struct {
+	Every   string `json:"every"`
+	Records int    `json:"records"`
+}
+
+// Lookup serves the single record of the endpoint dataset whose Field
+// equals the path parameter Param, e.g. /api/threats/:id
+type Lookup struct {
+	Param string `json:"param,omitempty"`
+	Field string `json:"field,omitempty"`
+}
+
+// Matcher selects the endpoint among the endpoints sharing the same method
+// and path. Key is the query parameter or header name, or the JSON path of
+// the value in the request body.
+type Matcher struct {
+	Location string `json:"location"`
+	Key      string `json:"key"`
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
+type Pagination struct {
 	Type     string         `json:"type"`
 	Location string         `json:"location"`
 	Options  map[string]any `json:"options,omitempty"`
 }
 
+// endpointTypes are the supported endpoint types, an empty type serves the response file
+var endpointTypes = map[string]bool{
+	"":          true,
+	"resource":  true,
+	"graphql":   true,
+	"sse":       true,
+	"websocket": true,
+	"job":       true,
+}
+
+// LoadConfig loads the config file of the CONFIG_FILE_PATH environment variable
 func LoadConfig() (*APIConfig, error) {
-	var (
-		mockLogger     = logger.GetLogger()
-		configFilePath = os.Getenv("CONFIG_FILE_PATH")
-	)
+	return LoadConfigFile(os.Getenv("CONFIG_FILE_PATH"))
+}
+
+// LoadConfigFile loads and validates the given config file
+func LoadConfigFile(configFilePath string) (*APIConfig, error) {
+	var mockLogger = logger.GetLogger()
 
 	apiConfig := &APIConfig{}
 
@@ -59,6 +413,11 @@ func LoadConfig() (*APIConfig, error) {
 	return apiConfig, nil
 }
 
+// Validate checks the config, e.g. after it is changed by the command line
+func Validate(cfg *APIConfig) error {
+	return validateConfig(cfg)
+}
+
 func validateConfig(cfg *APIConfig) error {
 	var mockLogger = logger.GetLogger()
 
@@ -67,15 +426,546 @@ func validateConfig(cfg *APIConfig) error {
 		return errInvalidConfig
 	}
 
-	for _, endpoint := range cfg.Endpoints {
+	if cfg.Record != nil {
+		upstream, err := url.Parse(cfg.Record.Upstream)
+		if err != nil || upstream.Scheme == "" || upstream.Host == "" {
+			mockLogger.Warn("invalid record upstream", errInvalidUpstream)
+			return errInvalidUpstream
+		}
+		if cfg.Record.ConfigFile == "" {
+			mockLogger.Warn("invalid record config file", errInvalidRecordFile)
+			return errInvalidRecordFile
+		}
+	}
+
+	if cfg.Persistence != nil {
+		if cfg.Persistence.File == "" {
+			mockLogger.Warn("invalid persistence file", errInvalidPersistence)
+			return errInvalidPersistence
+		}
+		if cfg.Persistence.Interval != "" {
+			if _, err := time.ParseDuration(cfg.Persistence.Interval); err != nil {
+				mockLogger.Warn("invalid persistence interval", err)
+				return errInvalidPersistence
+			}
+		}
+	}
+
+	if cfg.Protocols != nil && cfg.Protocols.H2C && cfg.Protocols.HTTP1Only {
+		mockLogger.Warn("invalid protocols config", errInvalidProtocols)
+		return errInvalidProtocols
+	}
+
+	if cfg.Timeouts != nil {
+		for _, d := range []string{cfg.Timeouts.Read, cfg.Timeouts.Write, cfg.Timeouts.Idle} {
+			if v, err := time.ParseDuration(d); d != "" && (err != nil || v < 0) {
+				mockLogger.Warn("invalid timeouts config", errInvalidTimeouts)
+				return errInvalidTimeouts
+			}
+		}
+	}
+
+	if err := validateTLS(cfg.TLS); err != nil {
+		mockLogger.Warn("invalid tls config", err)
+		return err
+	}
+
+	if err := validateWebhooks(cfg.Webhooks); err != nil {
+		mockLogger.Warn("invalid webhooks config", err)
+		return err
+	}
+
+	if err := validateGRPC(cfg.GRPC); err != nil {
+		mockLogger.Warn("invalid grpc config", err)
+		return err
+	}
+
+	if err := validateEndpoints(cfg.Endpoints); err != nil {
+		return err
+	}
+
+	if err := validateVirtualHosts(cfg.VirtualHosts, os.Getenv("PORT"), cfg.GRPC); err != nil {
+		mockLogger.Warn("invalid virtual hosts", err)
+		return err
+	}
+
+	return nil
+}
+
+// httpMethods are the methods an endpoint can be served on
+var httpMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+func validateEndpoints(endpoints []Endpoint) error {
+	var mockLogger = logger.GetLogger()
+
+	for i, endpoint := range endpoints {
 		if endpoint.Path == "" {
 			mockLogger.Warn("invalid endpoint path", errInvalidPath)
 			return errInvalidPath
 		}
-		if endpoint.Method == "" {
+		// The routes are registered with the method in upper case
+		endpoint.Method = strings.ToUpper(endpoint.Method)
+		endpoints[i].Method = endpoint.Method
+		if endpoint.Method != "" && !httpMethods[endpoint.Method] {
+			mockLogger.Warn("invalid endpoint method", errInvalidMethod)
+			return errInvalidMethod
+		}
+		// Resources serve all the methods of their operations, GraphQL endpoints GET and
+		// POST and websocket endpoints GET
+		if endpoint.Method == "" && endpoint.Type != "resource" && endpoint.Type != "graphql" && endpoint.Type != "websocket" {
 			mockLogger.Warn("invalid endpoint method", errInvalidMethod)
 			return errInvalidMethod
 		}
+		if !endpointTypes[endpoint.Type] {
+			mockLogger.Warn("invalid endpoint type", errInvalidType)
+			return errInvalidType
+		}
+		if err := validateTimeline(endpoint.Timeline); err != nil {
+			mockLogger.Warn("invalid endpoint timeline", err)
+			return err
+		}
+		if endpoint.Type == "graphql" && (endpoint.GraphQL == nil || endpoint.GraphQL.SchemaFile == "") {
+			mockLogger.Warn("invalid graphql endpoint", errInvalidGraphQL)
+			return errInvalidGraphQL
+		}
+		if err := validateSSE(endpoint.SSE); err != nil {
+			mockLogger.Warn("invalid sse endpoint", err)
+			return err
+		}
+		if err := validateWebSocket(endpoint.WebSocket); err != nil {
+			mockLogger.Warn("invalid websocket endpoint", err)
+			return err
+		}
+		if err := validateJob(endpoint); err != nil {
+			mockLogger.Warn("invalid job endpoint", err)
+			return err
+		}
+		if err := validateExport(endpoint.Export); err != nil {
+			mockLogger.Warn("invalid endpoint export", err)
+			return err
+		}
+		if err := validateLookup(endpoint); err != nil {
+			mockLogger.Warn("invalid endpoint lookup", err)
+			return err
+		}
+		// Mutations change the dataset of the response file, resources and timelines have their own
+		if len(endpoint.Mutations) > 0 && (endpoint.Type == "resource" || endpoint.Timeline != nil) {
+			mockLogger.Warn("mutations on a resource or timeline", errInvalidMutation)
+			return errInvalidMutation
+		}
+		if len(endpoint.Mutations) > 0 && !servesDataset(endpoint) {
+			mockLogger.Warn("mutations on an endpoint without a dataset", errInvalidMutation)
+			return errInvalidMutation
+		}
+		for _, mutation := range endpoint.Mutations {
+			if mutation.Action != "insert" && mutation.Action != "delete" && mutation.Action != "reorder" {
+				mockLogger.Warn("invalid mutation action", errInvalidMutation)
+				return errInvalidMutation
+			}
+			if mutation.AfterRequest < 0 || mutation.Every < 0 || mutation.Count < 0 {
+				mockLogger.Warn("invalid mutation schedule", errInvalidMutation)
+				return errInvalidMutation
+			}
+		}
+		for _, matcher := range endpoint.Matchers {
+			if matcher.Location != "query" && matcher.Location != "header" && matcher.Location != "body" {
+				mockLogger.Warn("invalid matcher location", errInvalidMatcher)
+				return errInvalidMatcher
+			}
+			if matcher.Key == "" {
+				mockLogger.Warn("invalid matcher key", errInvalidMatcher)
+				return errInvalidMatcher
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateVirtualHosts(hosts []VirtualHost, port string, grpc *GRPC) error {
+	names := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		if host.Name == "" || names[host.Name] {
+			return errInvalidVirtualHost
+		}
+		names[host.Name] = true
+
+		// A virtual host listens on its own port or serves the requests to its hosts
+		if host.Port == "" && len(host.Hosts) == 0 {
+			return errInvalidVirtualHost
+		}
+		// Its own port is neither the main port nor the gRPC one
+		if host.Port != "" && (host.Port == port || (grpc != nil && host.Port == grpc.Port)) {
+			return errInvalidVirtualHost
+		}
+		if host.BasePath != "" && !strings.HasPrefix(host.BasePath, "/") {
+			return errInvalidVirtualHost
+		}
+		if err := validateAuth(host.Auth); err != nil {
+			return err
+		}
+		if err := validateEndpoints(host.Endpoints); err != nil {
+			return errors.Join(errInvalidVirtualHost, err)
+		}
+	}
+
+	return nil
+}
+
+func validateAuth(auth *Auth) error {
+	if auth == nil {
+		return nil
+	}
+
+	switch auth.Type {
+	case "bearer":
+		if len(auth.Tokens) == 0 {
+			return errInvalidAuth
+		}
+	case "apiKey":
+		if len(auth.Tokens) == 0 || (auth.Header != "" && auth.Query != "") {
+			return errInvalidAuth
+		}
+	case "basic":
+		if auth.Username == "" {
+			return errInvalidAuth
+		}
+	default:
+		return errInvalidAuth
+	}
+
+	return nil
+}
+
+// timelineOperators are the comparisons supported by the timeline filters
+var timelineOperators = map[string]bool{
+	"gt":  true,
+	"gte": true,
+	"lt":  true,
+	"lte": true,
+}
+
+func validateTimeline(timeline *Timeline) error {
+	if timeline == nil {
+		return nil
+	}
+
+	if timeline.Start != "" {
+		if _, err := time.Parse(time.RFC3339, timeline.Start); err != nil {
+			return errors.Join(errInvalidTimeline, err)
+		}
+	}
+	if timeline.Step != "" {
+		if _, err := time.ParseDuration(timeline.Step); err != nil {
+			return errors.Join(errInvalidTimeline, err)
+		}
+	}
+	for _, operator := range timeline.Filters {
+		if !timelineOperators[operator] {
+			return errInvalidTimeline
+		}
+	}
+	if timeline.Advance != nil {
+		every, err := time.ParseDuration(timeline.Advance.Every)
+		if err != nil || every <= 0 || timeline.Advance.Records <= 0 {
+			return errInvalidTimeline
+		}
+	}
+
+	return nil
+}
+
+// servesDataset reports whether the endpoint serves the dataset of its response
+// file, e.g. paginated, rather than the response object as it is
+func servesDataset(endpoint Endpoint) bool {
+	switch endpoint.Type {
+	case "sse":
+		return true
+	case "":
+	default:
+		return false
+	}
+
+	if endpoint.Export != nil || endpoint.Lookup != nil || endpoint.Query != nil {
+		return true
+	}
+	return endpoint.Pagination.Type != "" && endpoint.Pagination.Type != "none"
+}
+
+// validateLookup checks that a detail endpoint looks records up by the idKey of
+// its dataset, which the repeated records of the list endpoint are renumbered by
+func validateLookup(endpoint Endpoint) error {
+	// Resources are their own dataset, the lookup field is their id field
+	if endpoint.Lookup == nil || endpoint.Lookup.Field == "" || endpoint.Type == "resource" {
+		return nil
+	}
+
+	idKey, _ := endpoint.Pagination.Options["idKey"].(string)
+	if idKey == "" {
+		idKey = "id"
+	}
+	if endpoint.Lookup.Field != idKey {
+		return errInvalidLookup
+	}
+
+	return nil
+}
+
+func validateExport(export *Export) error {
+	if export == nil {
+		return nil
+	}
+
+	if export.Format != "csv" && export.Format != "ndjson" {
+		return errInvalidExport
+	}
+	if export.Chunking != "" && export.Chunking != "token" && export.Chunking != "range" {
+		return errInvalidExport
+	}
+	if export.ChunkSize < 0 || len([]rune(export.Delimiter)) > 1 {
+		return errInvalidExport
+	}
+	// Byte ranges are served from the complete file
+	if export.Chunking == "range" && export.Stream {
+		return errInvalidExport
+	}
+	if export.StreamDelay != "" {
+		if _, err := time.ParseDuration(export.StreamDelay); err != nil {
+			return errors.Join(errInvalidExport, err)
+		}
+	}
+	for _, column := range export.Columns {
+		if column.Name == "" {
+			return errInvalidExport
+		}
+	}
+
+	return nil
+}
+
+// ResolveFilePath resolves a file path referenced from the config (e.g.
+// responseObjFilePath) to the location it is read from and written to.
+// Absolute paths are left as they are.
+func ResolveFilePath(path string) string {
+	// Clean the path (removes ./ ../ etc.)
+	cleanPath := filepath.Clean(path)
+	if filepath.IsAbs(cleanPath) {
+		return cleanPath
+	}
+
+	return filepath.Join(".././", cleanPath)
+}
+
+func validateSSE(sse *SSE) error {
+	if sse == nil {
+		return nil
+	}
+
+	for _, d := range []string{sse.Interval, sse.Heartbeat} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d); err != nil {
+			return errors.Join(errInvalidSSE, err)
+		}
+	}
+	if sse.Retry < 0 || sse.DisconnectAfter < 0 || sse.DisconnectProbability < 0 || sse.DisconnectProbability > 1 {
+		return errInvalidSSE
+	}
+	if sse.DisconnectMode != "" && sse.DisconnectMode != "close" && sse.DisconnectMode != "abort" {
+		return errInvalidSSE
+	}
+
+	return nil
+}
+
+// jobStatuses are the statuses of a job that can be renamed
+var jobStatuses = map[string]bool{
+	"pending":  true,
+	"running":  true,
+	"complete": true,
+	"failed":   true,
+}
+
+func validateJob(endpoint Endpoint) error {
+	job := endpoint.Job
+	if job == nil {
+		return nil
+	}
+	if endpoint.Type != "job" {
+		return errInvalidJob
+	}
+
+	for _, path := range []string{job.StatusPath, job.ResultPath} {
+		if path != "" && !slices.Contains(strings.Split(path, "/"), ":jobId") {
+			return errInvalidJob
+		}
+	}
+	for _, d := range []string{job.PendingDuration, job.RunningDuration} {
+		if d == "" {
+			continue
+		}
+		if v, err := time.ParseDuration(d); err != nil || v < 0 {
+			return errors.Join(errInvalidJob, err)
+		}
+	}
+	if job.FailProbability < 0 || job.FailProbability > 1 {
+		return errInvalidJob
+	}
+	for status := range job.Statuses {
+		if !jobStatuses[status] {
+			return errInvalidJob
+		}
+	}
+
+	return nil
+}
+
+func validateWebSocket(ws *WebSocket) error {
+	if ws == nil {
+		return nil
+	}
+
+	durations := []string{ws.PingInterval, ws.PongTimeout}
+	messages := ws.Messages
+	closes := []*WebSocketClose{ws.Close}
+	for _, rule := range ws.Rules {
+		messages = append(messages, rule.Responses...)
+		closes = append(closes, rule.Close)
+		for _, matcher := range rule.Matchers {
+			if (matcher.Location != "" && matcher.Location != "body") || matcher.Key == "" {
+				return errInvalidWebSocket
+			}
+		}
+	}
+	for _, message := range messages {
+		durations = append(durations, message.Delay)
+	}
+	for _, c := range closes {
+		if c == nil {
+			continue
+		}
+		// 1005, 1006 and 1015 are reserved, they are never sent in a close frame
+		if c.Code < 1000 || c.Code > 4999 || c.Code == 1005 || c.Code == 1006 || c.Code == 1015 {
+			return errInvalidWebSocket
+		}
+		durations = append(durations, c.Delay)
+	}
+	for _, d := range durations {
+		if d == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d); err != nil {
+			return errors.Join(errInvalidWebSocket, err)
+		}
+	}
+
+	if ws.DisconnectAfter < 0 || ws.DisconnectProbability < 0 || ws.DisconnectProbability > 1 {
+		return errInvalidWebSocket
+	}
+	if ws.DisconnectMode != "" && ws.DisconnectMode != "close" && ws.DisconnectMode != "abort" {
+		return errInvalidWebSocket
+	}
+
+	return nil
+}
+
+func validateTLS(tls *TLS) error {
+	if tls == nil {
+		return nil
+	}
+
+	// The certificate and its key are given together, or both generated
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		return errInvalidTLS
+	}
+	switch tls.ClientAuth {
+	case "", "none":
+	case "optional", "require":
+		// A given certificate comes from a CA the generated one can not verify
+		if tls.CertFile != "" && tls.ClientCAFile == "" {
+			return errInvalidTLS
+		}
+		// The clients get their certificate of the generated CA from the output directory
+		if tls.ClientCAFile == "" && tls.OutputDir == "" {
+			return errInvalidTLS
+		}
+	default:
+		return errInvalidTLS
+	}
+
+	return nil
+}
+
+// signatureAlgorithms are the hash functions of the webhook signatures
+var signatureAlgorithms = map[string]bool{
+	"":       true,
+	"sha1":   true,
+	"sha256": true,
+	"sha512": true,
+}
+
+func validateWebhooks(webhooks *Webhooks) error {
+	if webhooks == nil {
+		return nil
+	}
+
+	if !signatureAlgorithms[webhooks.SignatureAlgorithm] {
+		return errInvalidWebhooks
+	}
+	if webhooks.MaxRetries < 0 || webhooks.DisableAfter < 0 {
+		return errInvalidWebhooks
+	}
+
+	durations := []string{webhooks.RetryBackoff, webhooks.Timeout}
+	names := make(map[string]bool, len(webhooks.Events))
+	for _, event := range webhooks.Events {
+		if event.Name == "" || names[event.Name] {
+			return errInvalidWebhooks
+		}
+		names[event.Name] = true
+		durations = append(durations, event.Schedule)
+	}
+	for _, d := range durations {
+		if d == "" {
+			continue
+		}
+		if v, err := time.ParseDuration(d); err != nil || v <= 0 {
+			return errors.Join(errInvalidWebhooks, err)
+		}
+	}
+
+	return nil
+}
+
+func validateGRPC(grpc *GRPC) error {
+	if grpc == nil {
+		return nil
+	}
+
+	if grpc.Port == "" {
+		return errInvalidGRPC
+	}
+	if len(grpc.ProtoFiles) == 0 && grpc.DescriptorSet == "" {
+		return errInvalidGRPC
+	}
+	for _, method := range grpc.Methods {
+		if method.ResponseObjFilePath == "" && (method.Status == "" || method.Status == "OK") {
+			return errInvalidGRPC
+		}
+		if method.StreamDelay != "" {
+			if _, err := time.ParseDuration(method.StreamDelay); err != nil {
+				return errors.Join(errInvalidGRPC, err)
+			}
+		}
 	}
 
 	return nil
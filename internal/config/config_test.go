@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateEndpointMethod(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		want   string
+		err    error
+	}{
+		{"upper case", "POST", "POST", nil},
+		{"lower case", "post", "POST", nil},
+		{"unknown", "FETCH", "", errInvalidMethod},
+		{"missing", "", "", errInvalidMethod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoints := []Endpoint{{Path: "/exports", Method: tt.method, Type: "job", Job: &Job{}}}
+
+			err := validateEndpoints(endpoints)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("validateEndpoints() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && endpoints[0].Method != tt.want {
+				t.Errorf("method = %q, want %q", endpoints[0].Method, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateEndpointMutations(t *testing.T) {
+	mutations := []Mutation{{Action: "insert", AfterRequest: 1, Count: 1}}
+
+	tests := []struct {
+		name     string
+		endpoint Endpoint
+		err      error
+	}{
+		{"page pagination", Endpoint{Pagination: Pagination{Type: "page"}}, nil},
+		{"export", Endpoint{Export: &Export{Format: "csv"}}, nil},
+		{"sse", Endpoint{Type: "sse"}, nil},
+		{"query", Endpoint{Pagination: Pagination{Type: "none"}, Query: &Query{}}, nil},
+		{"none pagination", Endpoint{Pagination: Pagination{Type: "none"}}, errInvalidMutation},
+		{"no pagination", Endpoint{}, errInvalidMutation},
+		{"resource", Endpoint{Type: "resource"}, errInvalidMutation},
+		{"websocket", Endpoint{Type: "websocket"}, errInvalidMutation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := tt.endpoint
+			endpoint.Path = "/records"
+			endpoint.Method = "GET"
+			endpoint.Mutations = mutations
+
+			if err := validateEndpoints([]Endpoint{endpoint}); !errors.Is(err, tt.err) {
+				t.Errorf("validateEndpoints() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts Timeouts
+		err      error
+	}{
+		{"defaults", Timeouts{}, nil},
+		{"durations", Timeouts{Read: "1s", Write: "2m", Idle: "0s"}, nil},
+		{"invalid", Timeouts{Write: "forever"}, errInvalidTimeouts},
+		{"negative", Timeouts{Read: "-1s"}, errInvalidTimeouts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(&APIConfig{Timeouts: &tt.timeouts}); !errors.Is(err, tt.err) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateVirtualHosts(t *testing.T) {
+	grpc := &GRPC{Port: "9090"}
+
+	tests := []struct {
+		name  string
+		hosts []VirtualHost
+		err   error
+	}{
+		{"own port", []VirtualHost{{Name: "a", Port: "8081"}}, nil},
+		{"hosts on the main port", []VirtualHost{{Name: "a", Hosts: []string{"a.local"}}, {Name: "b", Hosts: []string{"*.b.local"}}}, nil},
+		{"neither port nor hosts", []VirtualHost{{Name: "a"}}, errInvalidVirtualHost},
+		{"main port", []VirtualHost{{Name: "a", Port: "8080"}}, errInvalidVirtualHost},
+		{"grpc port", []VirtualHost{{Name: "a", Port: "9090"}}, errInvalidVirtualHost},
+		{"duplicate name", []VirtualHost{{Name: "a", Port: "8081"}, {Name: "a", Port: "8082"}}, errInvalidVirtualHost},
+		{"relative base path", []VirtualHost{{Name: "a", Port: "8081", BasePath: "v1"}}, errInvalidVirtualHost},
+		{"bearer without tokens", []VirtualHost{{Name: "a", Port: "8081", Auth: &Auth{Type: "bearer"}}}, errInvalidAuth},
+		{"api key in header and query", []VirtualHost{{Name: "a", Port: "8081", Auth: &Auth{Type: "apiKey", Tokens: []string{"k"}, Header: "X-Key", Query: "key"}}}, errInvalidAuth},
+		{"basic without username", []VirtualHost{{Name: "a", Port: "8081", Auth: &Auth{Type: "basic"}}}, errInvalidAuth},
+		{"unknown auth type", []VirtualHost{{Name: "a", Port: "8081", Auth: &Auth{Type: "digest"}}}, errInvalidAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateVirtualHosts(tt.hosts, "8080", grpc); !errors.Is(err, tt.err) {
+				t.Errorf("validateVirtualHosts() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
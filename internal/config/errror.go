@@ -3,7 +3,29 @@ package config
 import "errors"
 
 var (
-	errInvalidConfig = errors.New("invalid configuration")
-	errInvalidPath   = errors.New("invalid endpoint path")
-	errInvalidMethod = errors.New("invalid HTTP method for endpoint")
+	errInvalidConfig    = errors.New("invalid configuration")
+	errInvalidPath      = errors.New("invalid endpoint path")
+	errInvalidMethod    = errors.New("invalid HTTP method for endpoint")
+	errInvalidMatcher   = errors.New("invalid request matcher for endpoint")
+	errInvalidType      = errors.New("invalid endpoint type")
+	errInvalidTimeline  = errors.New("invalid endpoint timeline")
+	errInvalidMutation  = errors.New("invalid endpoint mutation")
+	errInvalidExport    = errors.New("invalid endpoint export")
+	errInvalidGraphQL   = errors.New("invalid graphql endpoint")
+	errInvalidSSE       = errors.New("invalid sse endpoint")
+	errInvalidWebSocket = errors.New("invalid websocket endpoint")
+	errInvalidLookup    = errors.New("invalid endpoint lookup")
+
+	errInvalidUpstream   = errors.New("invalid record upstream url")
+	errInvalidRecordFile = errors.New("invalid record config file")
+
+	errInvalidPersistence = errors.New("invalid persistence config")
+	errInvalidGRPC        = errors.New("invalid grpc config")
+	errInvalidWebhooks    = errors.New("invalid webhooks config")
+	errInvalidJob         = errors.New("invalid job endpoint")
+	errInvalidTLS         = errors.New("invalid tls config")
+	errInvalidProtocols   = errors.New("invalid protocols config")
+	errInvalidTimeouts    = errors.New("invalid timeouts config")
+	errInvalidVirtualHost = errors.New("invalid virtual host")
+	errInvalidAuth        = errors.New("invalid auth config")
 )
@@ -0,0 +1,113 @@
+package dataset
+
+import (
+	"fmt"
+
+	"mock-server/internal/jsonvalue"
+)
+
+// Build generates the records of an endpoint from the records of its
+// response file. The file records are repeated until total records exist;
+// the repeated records get a new value in idField so that every record of
+// the dataset can be looked up by its id.
+func Build(templates []any, total int, idField string) []any {
+	if len(templates) == 0 || total <= 0 {
+		return []any{}
+	}
+
+	var maxID float64
+	for _, t := range templates {
+		if record, ok := t.(map[string]any); ok {
+			if id, ok := record[idField].(float64); ok && id > maxID {
+				maxID = id
+			}
+		}
+	}
+
+	records := make([]any, 0, total)
+	for i := 0; i < total; i++ {
+		record := DeepCopy(templates[i%len(templates)])
+
+		if m, ok := record.(map[string]any); ok && i >= len(templates) {
+			switch id := m[idField].(type) {
+			case float64:
+				m[idField] = maxID + float64(i-len(templates)+1)
+			case string:
+				m[idField] = fmt.Sprintf("%s-%d", id, i+1)
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// Find returns the record whose idField equals the given id
+func Find(records []any, idField, id string) (any, bool) {
+	for _, r := range records {
+		record, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+		if value, ok := record[idField]; ok && IDString(value) == id {
+			return record, true
+		}
+	}
+	return nil, false
+}
+
+// IDString formats the id of a record like it appears in a path
+func IDString(id any) string {
+	return jsonvalue.String(id)
+}
+
+// Window returns the records in [start, start+size) of the given records
+func Window(records []any, start, size int) []any {
+	if start < 0 {
+		start = 0
+	}
+	if start >= len(records) || size <= 0 {
+		return []any{}
+	}
+
+	end := start + size
+	if end > len(records) {
+		end = len(records)
+	}
+
+	return records[start:end]
+}
+
+// DeepCopy copies the given JSON value
+func DeepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[key] = DeepCopy(item)
+		}
+		return m
+	case []any:
+		arr := make([]any, len(v))
+		for i, item := range v {
+			arr[i] = DeepCopy(item)
+		}
+		return arr
+	default:
+		return v
+	}
+}
+
+// Source provides the records an endpoint paginates over
+type Source interface {
+	Records() []any
+}
+
+// Static is a Source over a fixed set of records
+type Static []any
+
+// Records returns the records
+func (s Static) Records() []any {
+	return s
+}
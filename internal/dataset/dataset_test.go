@@ -0,0 +1,302 @@
+package dataset
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"mock-server/internal/state"
+)
+
+func TestIDString(t *testing.T) {
+	tests := []struct {
+		name string
+		id   any
+		want string
+	}{
+		{"string", "abc", "abc"},
+		{"small number", float64(42), "42"},
+		{"large number", float64(1000000), "1000000"},
+		{"larger number", float64(123456789012), "123456789012"},
+		{"fraction", 1.5, "1.5"},
+		{"int", 7, "7"},
+		{"bool", true, "true"},
+		{"null", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IDString(tt.id); got != tt.want {
+				t.Errorf("IDString(%v) = %q, want %q", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	records := []any{
+		map[string]any{"id": float64(1), "name": "a"},
+		map[string]any{"id": float64(1000000), "name": "b"},
+		map[string]any{"id": "usr-1", "name": "c"},
+		"not a record",
+	}
+
+	tests := []struct {
+		name     string
+		id       string
+		wantName string
+		found    bool
+	}{
+		{"small number", "1", "a", true},
+		{"large number", "1000000", "b", true},
+		{"exponent form", "1e+06", "", false},
+		{"string", "usr-1", "c", true},
+		{"missing", "2", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, found := Find(records, "id", tt.id)
+			if found != tt.found {
+				t.Fatalf("Find(%q) found = %v, want %v", tt.id, found, tt.found)
+			}
+			if found && record.(map[string]any)["name"] != tt.wantName {
+				t.Errorf("Find(%q) = %v, want name %q", tt.id, record, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates []any
+		total     int
+		want      []any
+	}{
+		{
+			name:      "number ids continue after the largest",
+			templates: []any{map[string]any{"id": float64(5)}, map[string]any{"id": float64(2)}},
+			total:     4,
+			want: []any{
+				map[string]any{"id": float64(5)},
+				map[string]any{"id": float64(2)},
+				map[string]any{"id": float64(6)},
+				map[string]any{"id": float64(7)},
+			},
+		},
+		{
+			name:      "string ids get their position",
+			templates: []any{map[string]any{"id": "a"}},
+			total:     3,
+			want: []any{
+				map[string]any{"id": "a"},
+				map[string]any{"id": "a-2"},
+				map[string]any{"id": "a-3"},
+			},
+		},
+		{
+			name:      "fewer records than templates",
+			templates: []any{map[string]any{"id": float64(1)}, map[string]any{"id": float64(2)}},
+			total:     1,
+			want:      []any{map[string]any{"id": float64(1)}},
+		},
+		{
+			name:      "no templates",
+			templates: nil,
+			total:     3,
+			want:      []any{},
+		},
+		{
+			name:      "no records",
+			templates: []any{map[string]any{"id": float64(1)}},
+			total:     0,
+			want:      []any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Build(tt.templates, tt.total, "id"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Build() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildCopiesTemplates(t *testing.T) {
+	templates := []any{map[string]any{"id": float64(1), "tags": []any{"x"}}}
+
+	records := Build(templates, 2, "id")
+	records[0].(map[string]any)["tags"].([]any)[0] = "changed"
+
+	if got := templates[0].(map[string]any)["tags"].([]any)[0]; got != "x" {
+		t.Errorf("template changed to %v", got)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	records := []any{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		name  string
+		start int
+		size  int
+		want  []any
+	}{
+		{"first page", 0, 2, []any{1, 2}},
+		{"middle page", 2, 2, []any{3, 4}},
+		{"last partial page", 4, 2, []any{5}},
+		{"past the end", 5, 2, []any{}},
+		{"negative start", -1, 2, []any{1, 2}},
+		{"no size", 0, 0, []any{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Window(records, tt.start, tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Window(%d, %d) = %v, want %v", tt.start, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMutable(t *testing.T) {
+	templates := []any{map[string]any{"id": float64(1)}, map[string]any{"id": float64(2)}, map[string]any{"id": float64(3)}}
+
+	tests := []struct {
+		name      string
+		mutations []Mutation
+		requests  int
+		want      []string
+	}{
+		{"no mutation before its request", []Mutation{{AfterRequest: 2, Action: Insert}}, 2, []string{"1", "2", "3"}},
+		{"insert at the head", []Mutation{{AfterRequest: 1, Action: Insert}}, 2, []string{"4", "1", "2", "3"}},
+		{"insert at the tail", []Mutation{{AfterRequest: 1, Action: Insert, Position: Tail}}, 2, []string{"1", "2", "3", "4"}},
+		{"delete at the head", []Mutation{{AfterRequest: 1, Action: Delete, Count: 2}}, 2, []string{"3"}},
+		{"delete every request", []Mutation{{AfterRequest: 1, Every: 1, Action: Delete, Position: Tail}}, 3, []string{"1"}},
+		{"reverse", []Mutation{{AfterRequest: 1, Action: Reorder, Position: Reverse}}, 2, []string{"3", "2", "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMutable(templates, 3, "id", tt.mutations)
+			for i := 0; i < tt.requests; i++ {
+				m.Request()
+			}
+			if got := ids(m.Records()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMutableRecordsDoNotChangeAfterReturn(t *testing.T) {
+	m := NewMutable([]any{map[string]any{"id": float64(1)}}, 2, "id", []Mutation{{AfterRequest: 1, Action: Delete}})
+	m.Request()
+	before := m.Records()
+	m.Request()
+
+	if got := ids(before); !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("returned records changed to %v", got)
+	}
+}
+
+func TestMutableSnapshotRestore(t *testing.T) {
+	templates := []any{map[string]any{"id": float64(1)}}
+	mutations := []Mutation{{AfterRequest: 1, Every: 1, Action: Insert}}
+
+	m := NewMutable(templates, 2, "id", mutations)
+	m.Request()
+	m.Request()
+	data, err := m.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewMutable(templates, 2, "id", mutations)
+	if err := state.Restore(restored, data); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ids(restored.Records()), ids(m.Records()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("restored records = %v, want %v", got, want)
+	}
+
+	// The next insert continues the ids of the restored records
+	m.Request()
+	restored.Request()
+	if got, want := ids(restored.Records()), ids(m.Records()); !reflect.DeepEqual(got, want) {
+		t.Errorf("records after restore = %v, want %v", got, want)
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	options := TimelineOptions{Field: "updated_at", Start: start, Step: time.Hour, Every: time.Minute, Records: 2}
+
+	tl := NewTimeline([]any{map[string]any{"id": float64(1)}}, 2, "id", options)
+	now := tl.startedAt
+	tl.now = func() time.Time { return now }
+
+	records := tl.Records()
+	if got := stamps(records); !reflect.DeepEqual(got, []string{"2024-01-01T00:00:00Z", "2024-01-01T01:00:00Z"}) {
+		t.Fatalf("stamps = %v", got)
+	}
+
+	now = now.Add(2*time.Minute + time.Second)
+	records = tl.Records()
+	if len(records) != 6 {
+		t.Fatalf("records after two advances = %d, want 6", len(records))
+	}
+	wantLast := tl.startedAt.Add(2 * time.Minute).UTC().Format(time.RFC3339)
+	if got := stamps(records)[5]; got != wantLast {
+		t.Errorf("newest stamp = %s, want %s", got, wantLast)
+	}
+	if got := ids(records); got[5] != "6" {
+		t.Errorf("newest id = %s, want 6", got[5])
+	}
+}
+
+func TestTimelineSnapshotRestore(t *testing.T) {
+	options := TimelineOptions{Field: "updated_at", Start: time.Now().Add(-time.Hour), Step: time.Second, Every: time.Minute, Records: 1}
+
+	tl := NewTimeline([]any{map[string]any{"id": float64(1)}}, 3, "id", options)
+	now := tl.startedAt.Add(5 * time.Minute)
+	tl.now = func() time.Time { return now }
+	tl.Records()
+
+	data, err := tl.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart with another start keeps the timestamps of the snapshot
+	options.Start = time.Now()
+	restored := NewTimeline([]any{map[string]any{"id": float64(1)}}, 3, "id", options)
+	restored.now = func() time.Time { return now }
+	if err := state.Restore(restored, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := stamps(restored.Records()), stamps(tl.Records()); !reflect.DeepEqual(got, want) {
+		t.Errorf("restored stamps = %v, want %v", got, want)
+	}
+}
+
+// ids returns the ids of the records
+func ids(records []any) []string {
+	result := make([]string, 0, len(records))
+	for _, r := range records {
+		result = append(result, IDString(r.(map[string]any)["id"]))
+	}
+	return result
+}
+
+// stamps returns the timestamps of the timeline records
+func stamps(records []any) []string {
+	result := make([]string, 0, len(records))
+	for _, r := range records {
+		result = append(result, r.(map[string]any)["updated_at"].(string))
+	}
+	return result
+}
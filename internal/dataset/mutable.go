@@ -0,0 +1,196 @@
+package dataset
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sync"
+
+	"mock-server/internal/state"
+)
+
+// MutationAction is the change a mutation applies to the records
+type MutationAction string
+
+// MutationPosition is where in the records a mutation applies
+type MutationPosition string
+
+const (
+	Insert  MutationAction = "insert"
+	Delete  MutationAction = "delete"
+	Reorder MutationAction = "reorder"
+
+	Head    MutationPosition = "head"
+	Tail    MutationPosition = "tail"
+	Random  MutationPosition = "random"
+	Reverse MutationPosition = "reverse"
+)
+
+// mutationSeed makes the random mutations repeatable across runs
+const mutationSeed = 1
+
+// Mutation changes the records after the AfterRequest-th request, and after
+// every Every requests when Every is set
+type Mutation struct {
+	AfterRequest int
+	Every        int
+	Action       MutationAction
+	Count        int
+	Position     MutationPosition
+}
+
+// Mutable is a Source whose records change between requests according to a
+// schedule of mutations, like the records of a real API change while a client
+// pages through them
+type Mutable struct {
+	mu        sync.Mutex
+	templates []any
+	total     int
+	idField   string
+	records   []any
+	inserted  int
+	requests  int
+	mutations []Mutation
+	random    *rand.Rand
+}
+
+var _ Source = (*Mutable)(nil)
+var _ state.Stateful = (*Mutable)(nil)
+
+// NewMutable creates the mutable dataset of total records built from the given templates
+func NewMutable(templates []any, total int, idField string, mutations []Mutation) *Mutable {
+	return &Mutable{
+		templates: templates,
+		total:     total,
+		idField:   idField,
+		records:   Build(templates, total, idField),
+		mutations: mutations,
+		random:    rand.New(rand.NewSource(mutationSeed)),
+	}
+}
+
+// Records returns the current records
+func (m *Mutable) Records() []any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.records
+}
+
+// Request applies the mutations scheduled after the previous request, it is
+// called at the start of every request
+func (m *Mutable) Request() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, mutation := range m.mutations {
+		due := m.requests > 0 && m.requests == mutation.AfterRequest
+		if mutation.Every > 0 && m.requests >= mutation.AfterRequest && m.requests > 0 {
+			due = (m.requests-mutation.AfterRequest)%mutation.Every == 0
+		}
+		if due {
+			m.apply(mutation)
+		}
+	}
+
+	m.requests++
+}
+
+// apply applies the mutation to a copy of the records, so that the records
+// returned earlier do not change
+func (m *Mutable) apply(mutation Mutation) {
+	records := make([]any, len(m.records))
+	copy(records, m.records)
+
+	count := mutation.Count
+	if count <= 0 {
+		count = 1
+	}
+
+	switch mutation.Action {
+	case Insert:
+		all := Build(m.templates, m.total+m.inserted+count, m.idField)
+		created := all[m.total+m.inserted:]
+		m.inserted += count
+
+		for _, record := range created {
+			i := m.position(mutation.Position, len(records)+1, Head)
+			records = append(records[:i], append([]any{record}, records[i:]...)...)
+		}
+	case Delete:
+		for n := 0; n < count && len(records) > 0; n++ {
+			i := m.position(mutation.Position, len(records), Head)
+			records = append(records[:i], records[i+1:]...)
+		}
+	case Reorder:
+		if mutation.Position == Reverse {
+			for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+				records[i], records[j] = records[j], records[i]
+			}
+		} else {
+			m.random.Shuffle(len(records), func(i, j int) {
+				records[i], records[j] = records[j], records[i]
+			})
+		}
+	}
+
+	m.records = records
+}
+
+// position returns the index in [0, n) the mutation applies at
+func (m *Mutable) position(position MutationPosition, n int, defaultPosition MutationPosition) int {
+	if position == "" {
+		position = defaultPosition
+	}
+
+	switch position {
+	case Tail:
+		return n - 1
+	case Random:
+		return m.random.Intn(n)
+	default:
+		return 0
+	}
+}
+
+// mutableState is the persisted state of a mutable dataset
+type mutableState struct {
+	Records  []any `json:"records"`
+	Inserted int   `json:"inserted"`
+	Requests int   `json:"requests"`
+}
+
+// Snapshot returns the records and the request count of the dataset
+func (m *Mutable) Snapshot() (json.RawMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return json.Marshal(mutableState{
+		Records:  m.records,
+		Inserted: m.inserted,
+		Requests: m.requests,
+	})
+}
+
+// Decode decodes the records and the request count of the dataset and returns
+// the function replacing them, the following mutations are scheduled from the
+// restored request count
+func (m *Mutable) Decode(data json.RawMessage) (func(), error) {
+	var s mutableState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		m.records = s.Records
+		if m.records == nil {
+			m.records = []any{}
+		}
+		m.inserted = s.Inserted
+		m.requests = s.Requests
+		// Keep the random mutations repeatable from the restored request on
+		m.random = rand.New(rand.NewSource(mutationSeed + int64(s.Requests)))
+	}, nil
+}
@@ -0,0 +1,132 @@
+package dataset
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"mock-server/internal/state"
+)
+
+// TimelineOptions configures the timestamps of a Timeline
+type TimelineOptions struct {
+	// Field is the record field holding the timestamp
+	Field string
+	// Start is the timestamp of the first record, the following records are Step apart
+	Start time.Time
+	Step  time.Duration
+	// Every interval Records new records appear, stamped with the time they appeared
+	Every   time.Duration
+	Records int
+}
+
+// Timeline is a Source whose records carry generated timestamps, ordered
+// from the oldest to the newest. When it advances, new records appear at
+// the end as time goes by.
+type Timeline struct {
+	mu        sync.Mutex
+	templates []any
+	total     int
+	idField   string
+	options   TimelineOptions
+	startedAt time.Time
+	advances  int
+	records   []any
+	now       func() time.Time
+}
+
+var _ Source = (*Timeline)(nil)
+var _ state.Stateful = (*Timeline)(nil)
+
+// NewTimeline creates a timeline of total records built from the given templates
+func NewTimeline(templates []any, total int, idField string, options TimelineOptions) *Timeline {
+	t := &Timeline{
+		templates: templates,
+		total:     total,
+		idField:   idField,
+		options:   options,
+		now:       time.Now,
+	}
+
+	t.startedAt = t.now()
+	t.records = t.build()
+
+	return t
+}
+
+// Records returns the records which exist at this time
+func (t *Timeline) Records() []any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.options.Every > 0 && t.options.Records > 0 {
+		due := int(t.now().Sub(t.startedAt) / t.options.Every)
+		if due > t.advances {
+			t.advances = due
+			t.records = t.build()
+		}
+	}
+
+	return t.records
+}
+
+// build generates the records of the timeline with their timestamps
+func (t *Timeline) build() []any {
+	records := Build(t.templates, t.total+t.advances*t.options.Records, t.idField)
+
+	for i, r := range records {
+		record, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		stamp := t.options.Start.Add(time.Duration(i) * t.options.Step)
+		if i >= t.total {
+			advance := (i-t.total)/t.options.Records + 1
+			stamp = t.startedAt.Add(time.Duration(advance) * t.options.Every)
+		}
+
+		record[t.options.Field] = stamp.UTC().Format(time.RFC3339)
+	}
+
+	return records
+}
+
+// timelineState is the persisted state of a timeline, the records are rebuilt from it
+type timelineState struct {
+	Start     time.Time `json:"start"`
+	StartedAt time.Time `json:"startedAt"`
+	Advances  int       `json:"advances"`
+}
+
+// Snapshot returns the times the timestamps of the records are generated from
+func (t *Timeline) Snapshot() (json.RawMessage, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return json.Marshal(timelineState{
+		Start:     t.options.Start,
+		StartedAt: t.startedAt,
+		Advances:  t.advances,
+	})
+}
+
+// Decode decodes the given times and returns the function rebuilding the
+// records from them, so that the records keep their timestamps and the records
+// which appeared before the restart remain
+func (t *Timeline) Decode(data json.RawMessage) (func(), error) {
+	var s timelineState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		t.options.Start = s.Start
+		t.startedAt = s.StartedAt
+		t.advances = s.Advances
+		t.records = t.build()
+	}, nil
+}
@@ -0,0 +1,377 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"mock-server/internal/dataset"
+	"mock-server/internal/pagination"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// connectionArguments are the Relay pagination arguments, they never filter the records
+var connectionArguments = map[string]bool{
+	"first":  true,
+	"after":  true,
+	"last":   true,
+	"before": true,
+}
+
+// executor executes an operation over the response files of the resolvers
+type executor struct {
+	server *Server
+	vars   map[string]any
+	errors gqlerror.List
+}
+
+// object is a result object, its fields are written in the order of the query
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *object) set(key string, value any) {
+	if o.values == nil {
+		o.values = map[string]any{}
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// MarshalJSON writes the fields in the order of the query
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// selectionSet resolves the selected fields of the value of the given object type
+func (e *executor) selectionSet(selections ast.SelectionSet, def *ast.Definition, value map[string]any, path ast.Path) *object {
+	result := &object{}
+
+	keys, fields := e.collectFields(selections, def, nil, map[string][]*ast.Field{})
+	for _, key := range keys {
+		field := fields[key][0]
+
+		// The selections of the fields sharing the response key are merged
+		var sub ast.SelectionSet
+		for _, f := range fields[key] {
+			sub = append(sub, f.SelectionSet...)
+		}
+
+		fieldPath := append(append(ast.Path{}, path...), ast.PathName(key))
+		result.set(key, e.field(field, sub, def, value, fieldPath))
+	}
+
+	return result
+}
+
+// collectFields returns the fields of the selections by their response key,
+// in the order of the query, with the fragments applying to the type expanded
+func (e *executor) collectFields(selections ast.SelectionSet, def *ast.Definition, keys []string, fields map[string][]*ast.Field) ([]string, map[string][]*ast.Field) {
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if !e.included(s.Directives) {
+				continue
+			}
+			key := s.Alias
+			if key == "" {
+				key = s.Name
+			}
+			if _, ok := fields[key]; !ok {
+				keys = append(keys, key)
+			}
+			fields[key] = append(fields[key], s)
+		case *ast.InlineFragment:
+			if e.included(s.Directives) && e.applies(s.TypeCondition, def) {
+				keys, fields = e.collectFields(s.SelectionSet, def, keys, fields)
+			}
+		case *ast.FragmentSpread:
+			if e.included(s.Directives) && s.Definition != nil && e.applies(s.Definition.TypeCondition, def) {
+				keys, fields = e.collectFields(s.Definition.SelectionSet, def, keys, fields)
+			}
+		}
+	}
+
+	return keys, fields
+}
+
+// included tells whether the @skip and @include directives keep the selection
+func (e *executor) included(directives ast.DirectiveList) bool {
+	if d := directives.ForName("skip"); d != nil {
+		if skip, _ := d.ArgumentMap(e.vars)["if"].(bool); skip {
+			return false
+		}
+	}
+	if d := directives.ForName("include"); d != nil {
+		if include, _ := d.ArgumentMap(e.vars)["if"].(bool); !include {
+			return false
+		}
+	}
+	return true
+}
+
+// applies tells whether a fragment on the type condition applies to the object type
+func (e *executor) applies(typeCondition string, def *ast.Definition) bool {
+	if typeCondition == "" || typeCondition == def.Name {
+		return true
+	}
+
+	condition := e.server.schema.Types[typeCondition]
+	if condition == nil {
+		return false
+	}
+	for _, possible := range e.server.schema.GetPossibleTypes(condition) {
+		if possible.Name == def.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// field resolves the value of the field of the parent object
+func (e *executor) field(field *ast.Field, selections ast.SelectionSet, parent *ast.Definition, parentValue map[string]any, path ast.Path) any {
+	switch field.Name {
+	case "__typename":
+		return parent.Name
+	case "__schema", "__type":
+		e.errors = append(e.errors, gqlerror.ErrorPathf(path, "introspection is not supported"))
+		return nil
+	}
+
+	if field.Definition == nil {
+		e.errors = append(e.errors, gqlerror.ErrorPathf(path, "field %s is not supported", field.Name))
+		return nil
+	}
+
+	args := field.ArgumentMap(e.vars)
+
+	value, found := parentValue[field.Name]
+	var res *resolver
+	if r, ok := e.server.resolvers[parent.Name+"."+field.Name]; ok {
+		res = r
+		value, found = r.resolve(args), true
+	}
+	if !found {
+		return nil
+	}
+
+	// Connection fields over a list of records are paginated
+	if records, ok := value.([]any); ok && e.server.isConnection(field.Definition.Type) {
+		pageSize := defaultPageSize
+		if res != nil {
+			pageSize = res.pageSize
+		}
+
+		conn, err := connection(records, args, pageSize)
+		if err != nil {
+			e.errors = append(e.errors, gqlerror.ErrorPathf(path, "%s", err.Error()))
+			return nil
+		}
+		value = conn
+	}
+
+	return e.complete(field.Definition.Type, selections, value, path)
+}
+
+// complete shapes the value by the type of the field and its selections
+func (e *executor) complete(t *ast.Type, selections ast.SelectionSet, value any, path ast.Path) any {
+	if value == nil {
+		return nil
+	}
+
+	if t.Elem != nil {
+		list, ok := value.([]any)
+		if !ok {
+			e.errors = append(e.errors, gqlerror.ErrorPathf(path, "expected a list"))
+			return nil
+		}
+
+		result := make([]any, 0, len(list))
+		for i, item := range list {
+			itemPath := append(append(ast.Path{}, path...), ast.PathIndex(i))
+			result = append(result, e.complete(t.Elem, selections, item, itemPath))
+		}
+		return result
+	}
+
+	def := e.server.schema.Types[t.Name()]
+	if def == nil || def.IsLeafType() {
+		return value
+	}
+
+	obj, ok := value.(map[string]any)
+	if !ok {
+		e.errors = append(e.errors, gqlerror.ErrorPathf(path, "expected an object of type %s", def.Name))
+		return nil
+	}
+
+	// The concrete type of an interface or union is given by __typename, or is its first type
+	if def.IsAbstractType() {
+		possible := e.server.schema.GetPossibleTypes(def)
+		if len(possible) == 0 {
+			return nil
+		}
+		concrete := possible[0]
+		if name, ok := obj["__typename"].(string); ok {
+			for _, p := range possible {
+				if p.Name == name {
+					concrete = p
+				}
+			}
+		}
+		def = concrete
+	}
+
+	return e.selectionSet(selections, def, obj, path)
+}
+
+// resolve returns the value of the field for the given arguments
+func (r *resolver) resolve(args map[string]any) any {
+	if r.records == nil {
+		return r.value
+	}
+
+	if r.lookup != nil {
+		param, field := defaultLookupKey, defaultLookupKey
+		if r.lookup.Param != "" {
+			param = r.lookup.Param
+		}
+		if r.lookup.Field != "" {
+			field = r.lookup.Field
+		}
+
+		record, found := dataset.Find(r.records, field, dataset.IDString(args[param]))
+		if !found {
+			return nil
+		}
+		return record
+	}
+
+	// The arguments named like a field of the records filter the records
+	records := r.records
+	for name, value := range args {
+		if connectionArguments[name] || value == nil || !r.fields[name] {
+			continue
+		}
+
+		filtered := make([]any, 0, len(records))
+		for _, rec := range records {
+			record, ok := rec.(map[string]any)
+			if !ok {
+				continue
+			}
+			if actual, ok := record[name]; ok && dataset.IDString(actual) == dataset.IDString(value) {
+				filtered = append(filtered, record)
+			}
+		}
+		records = filtered
+	}
+
+	return records
+}
+
+// isConnection tells whether the type is a Relay connection, an object with edges and pageInfo
+func (s *Server) isConnection(t *ast.Type) bool {
+	if t.Elem != nil {
+		return false
+	}
+	def := s.schema.Types[t.Name()]
+	return def != nil && def.Kind == ast.Object && def.Fields.ForName("edges") != nil && def.Fields.ForName("pageInfo") != nil
+}
+
+// connection returns the Relay connection of the records selected by the
+// first/after and last/before arguments. The cursor of an edge is the
+// pagination cursor of its offset.
+func connection(records []any, args map[string]any, pageSize int) (map[string]any, error) {
+	start, end := 0, len(records)
+
+	if after, ok := args["after"].(string); ok {
+		offset, err := pagination.DecodeCursor(after)
+		if err != nil {
+			return nil, fmt.Errorf("invalid after cursor")
+		}
+		start = min(offset+1, len(records))
+	}
+	if before, ok := args["before"].(string); ok {
+		offset, err := pagination.DecodeCursor(before)
+		if err != nil {
+			return nil, fmt.Errorf("invalid before cursor")
+		}
+		end = max(min(offset, end), start)
+	}
+
+	first, hasFirst := intArgument(args, "first")
+	last, hasLast := intArgument(args, "last")
+	if (hasFirst && first < 0) || (hasLast && last < 0) {
+		return nil, fmt.Errorf("first and last must not be negative")
+	}
+	if !hasFirst && !hasLast {
+		first, hasFirst = pageSize, true
+	}
+	if hasFirst {
+		end = min(end, start+first)
+	}
+	if hasLast {
+		start = max(start, end-last)
+	}
+
+	edges := make([]any, 0, end-start)
+	nodes := make([]any, 0, end-start)
+	for i := start; i < end; i++ {
+		edges = append(edges, map[string]any{"cursor": pagination.EncodeCursor(i), "node": records[i]})
+		nodes = append(nodes, records[i])
+	}
+
+	pageInfo := map[string]any{
+		"hasNextPage":     end < len(records),
+		"hasPreviousPage": start > 0,
+		"startCursor":     nil,
+		"endCursor":       nil,
+	}
+	if end > start {
+		pageInfo["startCursor"] = pagination.EncodeCursor(start)
+		pageInfo["endCursor"] = pagination.EncodeCursor(end - 1)
+	}
+
+	return map[string]any{
+		"edges":      edges,
+		"nodes":      nodes,
+		"pageInfo":   pageInfo,
+		"totalCount": len(records),
+	}, nil
+}
+
+// intArgument returns the integer argument, the coerced variables are int64
+func intArgument(args map[string]any, name string) (int, bool) {
+	switch v := args[name].(type) {
+	case int64:
+		return int(v), true
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,241 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"mock-server/internal/config"
+	"mock-server/internal/pagination"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+const (
+	defaultLookupKey = "id"
+	defaultPageSize  = 100
+)
+
+var (
+	errCreateServer    = errors.New("failed to create graphql server")
+	errInvalidResolver = errors.New("invalid graphql resolver")
+)
+
+// Server serves the GraphQL API of an endpoint
+type Server struct {
+	path      string
+	schema    *ast.Schema
+	resolvers map[string]*resolver
+}
+
+// resolver serves the value of a field from a response file
+type resolver struct {
+	// records is the dataset of list, connection and lookup fields
+	records []any
+	// fields are the fields of the record type, the arguments named like them filter the records
+	fields   map[string]bool
+	value    any
+	lookup   *config.Lookup
+	pageSize int
+}
+
+// request is a GraphQL request sent as the JSON body or as query parameters
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// response is the GraphQL response
+type response struct {
+	Data   any           `json:"data,omitempty"`
+	Errors gqlerror.List `json:"errors,omitempty"`
+}
+
+// NewServer creates the GraphQL server of the given endpoint
+func NewServer(endpoint config.Endpoint) (*Server, error) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("creating graphql server", map[string]any{"endpoint": endpoint.Path})
+
+	sdl, err := os.ReadFile(config.ResolveFilePath(endpoint.GraphQL.SchemaFile))
+	if err != nil {
+		mockLogger.Warn("invalid graphql schema file", err)
+		return nil, errors.Join(errCreateServer, err)
+	}
+
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: endpoint.GraphQL.SchemaFile, Input: string(sdl)})
+	if err != nil {
+		mockLogger.Warn("invalid graphql schema", err)
+		return nil, errors.Join(errCreateServer, err)
+	}
+
+	s := &Server{
+		path:      endpoint.Path,
+		schema:    schema,
+		resolvers: make(map[string]*resolver, len(endpoint.GraphQL.Resolvers)),
+	}
+
+	for key, r := range endpoint.GraphQL.Resolvers {
+		res, err := s.newResolver(key, r)
+		if err != nil {
+			mockLogger.Warn(err.Error(), err)
+			return nil, errors.Join(errCreateServer, err)
+		}
+		s.resolvers[key] = res
+	}
+
+	return s, nil
+}
+
+// newResolver creates the resolver of the Type.field key
+func (s *Server) newResolver(key string, r config.Resolver) (*resolver, error) {
+	typeName, fieldName, found := strings.Cut(key, ".")
+	if !found {
+		return nil, fmt.Errorf("%w: %s must be written as Type.field", errInvalidResolver, key)
+	}
+
+	def := s.schema.Types[typeName]
+	if def == nil || def.Fields.ForName(fieldName) == nil {
+		return nil, fmt.Errorf("%w: %s is not a field of the schema", errInvalidResolver, key)
+	}
+	fieldType := def.Fields.ForName(fieldName).Type
+
+	endpoint := config.Endpoint{
+		Path:                key,
+		ResponseObjFilePath: r.ResponseObjFilePath,
+		ResponseField:       r.ResponseField,
+		Pagination:          r.Pagination,
+	}
+
+	res := &resolver{lookup: r.Lookup, pageSize: defaultPageSize}
+	if pageSize, ok := r.Pagination.Options["pageSize"].(float64); ok && pageSize > 0 {
+		res.pageSize = int(pageSize)
+	}
+
+	// Lists, connections and lookups are served from the dataset of the file
+	if fieldType.Elem != nil || s.isConnection(fieldType) || r.Lookup != nil {
+		records, err := pagination.LoadRecords(endpoint)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%w: %s", errInvalidResolver, key), err)
+		}
+		res.records = records
+
+		if def := s.recordType(fieldType); def != nil {
+			res.fields = make(map[string]bool, len(def.Fields))
+			for _, f := range def.Fields {
+				res.fields[f.Name] = true
+			}
+		}
+		return res, nil
+	}
+
+	value, err := pagination.LoadResponse(endpoint)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("%w: %s", errInvalidResolver, key), err)
+	}
+	res.value = value
+
+	return res, nil
+}
+
+// recordType returns the type of the records of a list or connection field
+func (s *Server) recordType(t *ast.Type) *ast.Definition {
+	if t.Elem != nil {
+		return s.schema.Types[t.Elem.Name()]
+	}
+	if !s.isConnection(t) {
+		return nil
+	}
+
+	edges := s.schema.Types[t.Name()].Fields.ForName("edges").Type
+	if edges.Elem == nil {
+		return nil
+	}
+	edge := s.schema.Types[edges.Elem.Name()]
+	if edge == nil || edge.Fields.ForName("node") == nil {
+		return nil
+	}
+	return s.schema.Types[edge.Fields.ForName("node").Type.Name()]
+}
+
+// Routes returns the GraphQL routes of the server
+func (s *Server) Routes() gin.RoutesInfo {
+	return gin.RoutesInfo{
+		{Method: http.MethodGet, Path: s.path, HandlerFunc: s.Serve},
+		{Method: http.MethodPost, Path: s.path, HandlerFunc: s.Serve},
+	}
+}
+
+// Serve is the handler function of the GraphQL endpoint
+func (s *Server) Serve(c *gin.Context) {
+	var req request
+
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if v := c.Query("variables"); v != "" {
+			if err := decodeJSON(strings.NewReader(v), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variables"})
+				return
+			}
+		}
+	} else if err := decodeJSON(c.Request.Body, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid graphql request"})
+		return
+	}
+
+	if req.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query is required"})
+		return
+	}
+
+	doc, errs := gqlparser.LoadQuery(s.schema, req.Query)
+	if len(errs) > 0 {
+		c.JSON(http.StatusOK, response{Errors: errs})
+		return
+	}
+
+	op := doc.Operations.ForName(req.OperationName)
+	if op == nil {
+		c.JSON(http.StatusOK, response{Errors: gqlerror.List{gqlerror.Errorf("operation %q not found", req.OperationName)}})
+		return
+	}
+
+	vars, err := validator.VariableValues(s.schema, op, req.Variables)
+	if err != nil {
+		c.JSON(http.StatusOK, response{Errors: gqlerror.List{gqlerror.WrapIfUnwrapped(err)}})
+		return
+	}
+
+	root := s.schema.Query
+	switch op.Operation {
+	case ast.Mutation:
+		root = s.schema.Mutation
+	case ast.Subscription:
+		c.JSON(http.StatusOK, response{Errors: gqlerror.List{gqlerror.Errorf("subscriptions are not supported")}})
+		return
+	}
+
+	e := &executor{server: s, vars: vars}
+	data := e.selectionSet(op.SelectionSet, root, nil, ast.Path{})
+
+	c.JSON(http.StatusOK, response{Data: data, Errors: e.errors})
+}
+
+// decodeJSON decodes the request JSON keeping the numbers as json.Number, which
+// the variables of the Int and ID types are coerced from
+func decodeJSON(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
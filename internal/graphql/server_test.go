@@ -0,0 +1,274 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testSchema = `
+type Query {
+  users(role: String, first: Int, after: String, last: Int, before: String): UserConnection!
+  userList(role: String): [User!]!
+  user(id: ID!): User
+  viewer: User
+}
+
+type UserConnection {
+  edges: [UserEdge!]!
+  nodes: [User!]!
+  pageInfo: PageInfo!
+  totalCount: Int!
+}
+
+type UserEdge {
+  cursor: String!
+  node: User!
+}
+
+type PageInfo {
+  hasNextPage: Boolean!
+  hasPreviousPage: Boolean!
+  startCursor: String
+  endCursor: String
+}
+
+type User {
+  id: ID!
+  name: String!
+  role: String!
+}
+`
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testServer returns a server over five users, the even ones are admins
+func testServer(t *testing.T) *Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	write := func(name string, v any) string {
+		path := filepath.Join(dir, name)
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	users := make([]any, 0, 5)
+	for i := 1; i <= 5; i++ {
+		role := "viewer"
+		if i%2 == 0 {
+			role = "admin"
+		}
+		users = append(users, map[string]any{"id": float64(999998 + i), "name": "user", "role": role})
+	}
+	usersFile := write("users.json", map[string]any{"users": users})
+
+	endpoint := config.Endpoint{
+		Path: "/graphql",
+		GraphQL: &config.GraphQL{
+			SchemaFile: write("schema.graphql", nil),
+			Resolvers: map[string]config.Resolver{
+				"Query.users":    {ResponseObjFilePath: usersFile, Pagination: config.Pagination{Options: map[string]any{"pageSize": float64(2)}}},
+				"Query.userList": {ResponseObjFilePath: usersFile},
+				"Query.user":     {ResponseObjFilePath: usersFile, Lookup: &config.Lookup{}},
+				"Query.viewer":   {ResponseObjFilePath: write("viewer.json", users[0])},
+			},
+		},
+	}
+	if err := os.WriteFile(endpoint.GraphQL.SchemaFile, []byte(testSchema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// query sends the query to the server and returns the decoded response
+func query(t *testing.T, s *Server, q string, variables map[string]any) map[string]any {
+	t.Helper()
+
+	engine := gin.New()
+	for _, route := range s.Routes() {
+		engine.Handle(route.Method, route.Path, route.HandlerFunc)
+	}
+
+	body, _ := json.Marshal(map[string]any{"query": q, "variables": variables})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(w, req)
+
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+	}
+	return resp
+}
+
+// path returns the value at the keys of the response
+func path(v any, keys ...string) any {
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+// nodeIDs returns the ids of the list of users
+func nodeIDs(v any) []string {
+	ids := []string{}
+	list, _ := v.([]any)
+	for _, item := range list {
+		if node, ok := item.(map[string]any)["node"]; ok {
+			item = node
+		}
+		ids = append(ids, dataset.IDString(item.(map[string]any)["id"]))
+	}
+	return ids
+}
+
+func TestConnection(t *testing.T) {
+	s := testServer(t)
+
+	tests := []struct {
+		name        string
+		args        string
+		ids         []string
+		hasNext     bool
+		hasPrevious bool
+	}{
+		{"default page size", "", []string{"999999", "1000000"}, true, false},
+		{"first", "(first: 3)", []string{"999999", "1000000", "1000001"}, true, false},
+		{"after", "(first: 2, after: \"" + pagination.EncodeCursor(1) + "\")", []string{"1000001", "1000002"}, true, true},
+		{"last", "(last: 2)", []string{"1000002", "1000003"}, false, true},
+		{"last before", "(last: 1, before: \"" + pagination.EncodeCursor(2) + "\")", []string{"1000000"}, true, true},
+		{"filtered", "(role: \"admin\")", []string{"1000000", "1000002"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := query(t, s, "{ users"+tt.args+" { edges { cursor node { id } } pageInfo { hasNextPage hasPreviousPage } totalCount } }", nil)
+			if errs := resp["errors"]; errs != nil {
+				t.Fatalf("errors: %v", errs)
+			}
+
+			users := path(resp, "data", "users")
+			if got := nodeIDs(path(users, "edges")); !reflect.DeepEqual(got, tt.ids) {
+				t.Errorf("ids = %v, want %v", got, tt.ids)
+			}
+			if got := path(users, "pageInfo", "hasNextPage"); got != tt.hasNext {
+				t.Errorf("hasNextPage = %v, want %v", got, tt.hasNext)
+			}
+			if got := path(users, "pageInfo", "hasPreviousPage"); got != tt.hasPrevious {
+				t.Errorf("hasPreviousPage = %v, want %v", got, tt.hasPrevious)
+			}
+		})
+	}
+}
+
+func TestConnectionCursorsContinue(t *testing.T) {
+	s := testServer(t)
+
+	var ids []string
+	after := ""
+	for pages := 0; pages < 5; pages++ {
+		args := "(first: 2)"
+		if after != "" {
+			args = "(first: 2, after: \"" + after + "\")"
+		}
+		resp := query(t, s, "{ users"+args+" { nodes { id } pageInfo { hasNextPage endCursor } } }", nil)
+		users := path(resp, "data", "users")
+		ids = append(ids, nodeIDs(path(users, "nodes"))...)
+
+		if path(users, "pageInfo", "hasNextPage") != true {
+			break
+		}
+		after = path(users, "pageInfo", "endCursor").(string)
+	}
+
+	if want := []string{"999999", "1000000", "1000001", "1000002", "1000003"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+}
+
+func TestConnectionInvalidArguments(t *testing.T) {
+	s := testServer(t)
+
+	for _, args := range []string{`(after: "invalid")`, `(first: -1)`} {
+		resp := query(t, s, "{ users"+args+" { totalCount } }", nil)
+		if resp["errors"] == nil {
+			t.Errorf("users%s succeeded", args)
+		}
+	}
+}
+
+func TestListFilter(t *testing.T) {
+	s := testServer(t)
+
+	resp := query(t, s, `{ userList(role: "viewer") { id } }`, nil)
+	if got, want := nodeIDs(path(resp, "data", "userList")), []string{"999999", "1000001", "1000003"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ids = %v, want %v", got, want)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	s := testServer(t)
+
+	tests := []struct {
+		name string
+		id   any
+		want any
+	}{
+		{"string id", "1000001", "1000001"},
+		{"number id", 1000002, "1000002"},
+		{"missing", "1", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := query(t, s, `query($id: ID!) { user(id: $id) { id role } }`, map[string]any{"id": tt.id})
+			got := path(resp, "data", "user", "id")
+			if got != nil {
+				got = dataset.IDString(got)
+			}
+			if got != tt.want {
+				t.Errorf("id = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelection(t *testing.T) {
+	s := testServer(t)
+
+	resp := query(t, s, `{ viewer { __typename name ... on User { id } me: id } }`, nil)
+	want := map[string]any{"__typename": "User", "name": "user", "id": float64(999999), "me": float64(999999)}
+	if got := path(resp, "data", "viewer"); !reflect.DeepEqual(got, want) {
+		t.Errorf("viewer = %v, want %v", got, want)
+	}
+}
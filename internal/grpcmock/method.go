@@ -0,0 +1,333 @@
+package grpcmock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/pagination"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// The AIP-158 pagination fields
+const (
+	pageSizeField      = "page_size"
+	pageTokenField     = "page_token"
+	nextPageTokenField = "next_page_token"
+	totalSizeField     = "total_size"
+	defaultPageSize    = 100
+)
+
+// method answers a gRPC method from its JSON fixture
+type method struct {
+	desc protoreflect.MethodDescriptor
+	// value is the fixture object of the responses
+	value map[string]any
+	// records are the messages of a stream, or the paginated repeated field
+	records     []any
+	recordField protoreflect.FieldDescriptor
+	paginated   bool
+	pageSize    int
+	streamDelay time.Duration
+	err         error
+}
+
+// newMethod creates the method of the full name from its config
+func newMethod(name string, desc protoreflect.MethodDescriptor, cfg config.GRPCMethod) (*method, error) {
+	m := &method{desc: desc, pageSize: defaultPageSize}
+
+	if cfg.StreamDelay != "" {
+		m.streamDelay, _ = time.ParseDuration(cfg.StreamDelay)
+	}
+	if pageSize, ok := cfg.Pagination.Options["pageSize"].(float64); ok && pageSize > 0 {
+		m.pageSize = int(pageSize)
+	}
+
+	// A status other than OK is returned as the error of every call
+	if cfg.Status != "" && cfg.Status != "OK" {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(cfg.Status))); err != nil {
+			return nil, fmt.Errorf("%w: invalid status %s of method %s", errInvalidMethod, cfg.Status, name)
+		}
+		m.err = status.Error(code, cfg.Message)
+		return m, nil
+	}
+
+	endpoint := config.Endpoint{
+		Path:                name,
+		ResponseObjFilePath: cfg.ResponseObjFilePath,
+		Pagination:          cfg.Pagination,
+	}
+
+	value, err := pagination.LoadResponse(endpoint)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("%w: %s", errInvalidMethod, name), err)
+	}
+
+	// The messages of a server stream are the records of a top level array
+	if _, ok := value.([]any); ok {
+		if !desc.IsStreamingServer() {
+			return nil, fmt.Errorf("%w: the response file of the unary method %s must be an object", errInvalidMethod, name)
+		}
+		if m.records, err = pagination.LoadRecords(endpoint); err != nil {
+			return nil, errors.Join(fmt.Errorf("%w: %s", errInvalidMethod, name), err)
+		}
+		return m, nil
+	}
+
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: the response file of method %s must be an object or an array", errInvalidMethod, name)
+	}
+	m.value = obj
+
+	// The responses with a next_page_token are paginated over their repeated field
+	output := desc.Output()
+	if output.Fields().ByName(nextPageTokenField) == nil || desc.Input().Fields().ByName(pageTokenField) == nil {
+		return m, m.validate(obj)
+	}
+
+	m.recordField = findRecordField(output, cfg.ResponseField)
+	if m.recordField == nil {
+		return nil, fmt.Errorf("%w: the response of method %s has no repeated field to paginate", errInvalidMethod, name)
+	}
+	m.paginated = true
+
+	endpoint.ResponseField = fixtureKey(obj, m.recordField)
+	if m.records, err = pagination.LoadRecords(endpoint); err != nil {
+		return nil, errors.Join(fmt.Errorf("%w: %s", errInvalidMethod, name), err)
+	}
+
+	return m, m.validate(m.page(0, 0))
+}
+
+// validate checks that the fixture converts to the response message, so that
+// an invalid fixture fails on start instead of on every call
+func (m *method) validate(value any) error {
+	if _, err := m.message(value); err != nil {
+		return errors.Join(fmt.Errorf("%w: invalid response file of method %s", errInvalidMethod, m.desc.FullName()), err)
+	}
+	return nil
+}
+
+// findRecordField returns the repeated field of the given name, or the first
+// repeated field of the message as AIP-158 specifies
+func findRecordField(output protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := output.Fields()
+	if name != "" {
+		field := fields.ByName(protoreflect.Name(name))
+		if field == nil {
+			field = fields.ByJSONName(name)
+		}
+		if field == nil || !field.IsList() {
+			return nil
+		}
+		return field
+	}
+
+	for i := 0; i < fields.Len(); i++ {
+		if fields.Get(i).IsList() {
+			return fields.Get(i)
+		}
+	}
+	return nil
+}
+
+// fixtureKey returns the key of the field in the fixture, protojson accepts
+// both the JSON name and the proto name
+func fixtureKey(obj map[string]any, field protoreflect.FieldDescriptor) string {
+	if _, ok := obj[string(field.Name())]; ok {
+		return string(field.Name())
+	}
+	return field.JSONName()
+}
+
+// setField replaces the field of the fixture, under both of its names
+func setField(obj map[string]any, field protoreflect.FieldDescriptor, value any) {
+	delete(obj, string(field.Name()))
+	obj[field.JSONName()] = value
+}
+
+// unary answers the request with the fixture, or its page for a paginated method
+func (m *method) unary(in *dynamicpb.Message) (proto.Message, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if !m.paginated {
+		return m.message(m.value)
+	}
+
+	start := 0
+	if token := stringField(in, pageTokenField); token != "" {
+		offset, err := pagination.DecodeCursor(token)
+		if err != nil || offset > len(m.records) {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		start = offset
+	}
+
+	pageSize := intField(in, pageSizeField)
+	if pageSize < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	}
+
+	return m.message(m.page(start, pageSize))
+}
+
+// page returns the fixture with the page of records starting at the offset and
+// its next_page_token, which is empty on the last page. A requested page size
+// overrides the page size of the method.
+func (m *method) page(start int, pageSize int64) map[string]any {
+	records, size := m.records, m.pageSize
+	if pageSize > 0 {
+		size = int(pageSize)
+	}
+
+	response := make(map[string]any, len(m.value)+2)
+	for k, v := range m.value {
+		response[k] = v
+	}
+
+	window := dataset.Window(records, start, size)
+	setField(response, m.recordField, window)
+
+	output := m.desc.Output().Fields()
+	nextPageToken := ""
+	if next := start + len(window); next < len(records) {
+		nextPageToken = pagination.EncodeCursor(next)
+	}
+	setField(response, output.ByName(nextPageTokenField), nextPageToken)
+
+	if totalSize := output.ByName(totalSizeField); totalSize != nil {
+		setField(response, totalSize, len(records))
+	}
+
+	return response
+}
+
+// stream sends the records one message at a time, or the fixture as a single message
+func (m *method) stream(ctx context.Context, send func(proto.Message) error) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	messages := m.records
+	switch {
+	case m.paginated:
+		messages = []any{m.page(0, 0)}
+	case messages == nil:
+		messages = []any{m.value}
+	}
+
+	for i, value := range messages {
+		if i > 0 && m.streamDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return status.FromContextError(ctx.Err()).Err()
+			case <-time.After(m.streamDelay):
+			}
+		}
+
+		msg, err := m.message(value)
+		if err != nil {
+			return err
+		}
+		if err := send(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// message converts the JSON value to the response message, the fields that the
+// message does not declare are ignored
+func (m *method) message(value any) (proto.Message, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create response message")
+	}
+
+	msg := dynamicpb.NewMessage(m.desc.Output())
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, msg); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create response message: %v", err)
+	}
+
+	return msg, nil
+}
+
+// unaryHandler returns the handler of the unary method, the methods without a fixture are unimplemented
+func unaryHandler(name string, desc protoreflect.MethodDescriptor, m *method) func(any, context.Context, func(any) error, grpc.UnaryServerInterceptor) (any, error) {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		in := dynamicpb.NewMessage(desc.Input())
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+
+		handler := func(ctx context.Context, req any) (any, error) {
+			if m == nil {
+				return nil, status.Errorf(codes.Unimplemented, "method %s is not mocked", name)
+			}
+			return m.unary(req.(*dynamicpb.Message))
+		}
+		if interceptor == nil {
+			return handler(ctx, in)
+		}
+		return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + name}, handler)
+	}
+}
+
+// streamHandler returns the handler of the streaming method. Only server
+// streams are mocked, the client and bidirectional streams are unimplemented.
+func streamHandler(name string, desc protoreflect.MethodDescriptor, m *method) grpc.StreamHandler {
+	return func(srv any, stream grpc.ServerStream) error {
+		if m == nil || desc.IsStreamingClient() {
+			return status.Errorf(codes.Unimplemented, "method %s is not mocked", name)
+		}
+
+		in := dynamicpb.NewMessage(desc.Input())
+		if err := stream.RecvMsg(in); err != nil {
+			return err
+		}
+
+		return m.stream(stream.Context(), func(msg proto.Message) error {
+			return stream.SendMsg(msg)
+		})
+	}
+}
+
+// stringField returns the string field of the request, empty when the message does not declare it
+func stringField(msg *dynamicpb.Message, name protoreflect.Name) string {
+	field := msg.Descriptor().Fields().ByName(name)
+	if field == nil || field.Kind() != protoreflect.StringKind {
+		return ""
+	}
+	return msg.Get(field).String()
+}
+
+// intField returns the integer field of the request, zero when the message does not declare it
+func intField(msg *dynamicpb.Message, name protoreflect.Name) int64 {
+	field := msg.Descriptor().Fields().ByName(name)
+	if field == nil {
+		return 0
+	}
+	switch field.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return msg.Get(field).Int()
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,271 @@
+package grpcmock
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mock-server/internal/config"
+	"mock-server/internal/pagination"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const testProto = `
+syntax = "proto3";
+
+package shop.v1;
+
+service OrderService {
+  rpc ListOrders(ListOrdersRequest) returns (ListOrdersResponse);
+  rpc GetOrder(GetOrderRequest) returns (Order);
+  rpc WatchOrders(ListOrdersRequest) returns (stream Order);
+}
+
+message Order {
+  string name = 1;
+  int64 total_cents = 2;
+}
+
+message ListOrdersRequest {
+  int32 page_size = 1;
+  string page_token = 2;
+}
+
+message ListOrdersResponse {
+  repeated Order orders = 1;
+  string next_page_token = 2;
+  int32 total_size = 3;
+}
+
+message GetOrderRequest {
+  string name = 1;
+}
+`
+
+// testServer returns a server mocking the order service over five orders
+func testServer(t *testing.T, methods map[string]config.GRPCMethod) *Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "shop.proto"), []byte(testProto), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	orders := make([]any, 0, 5)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		orders = append(orders, map[string]any{"name": name, "totalCents": "100"})
+	}
+	fixtures := map[string]any{
+		"orders.json": map[string]any{"orders": orders},
+		"stream.json": orders,
+		"order.json":  orders[0],
+	}
+	for name, v := range fixtures {
+		data, _ := json.Marshal(v)
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, m := range methods {
+		if m.ResponseObjFilePath != "" {
+			m.ResponseObjFilePath = filepath.Join(dir, m.ResponseObjFilePath)
+			methods[name] = m
+		}
+	}
+
+	s, err := NewServer(&config.GRPC{
+		Port:        "0",
+		ProtoFiles:  []string{"shop.proto"},
+		ImportPaths: []string{dir},
+		Methods:     methods,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// listRequest returns a ListOrdersRequest with the page size and token
+func listRequest(t *testing.T, s *Server, pageSize int32, pageToken string) *dynamicpb.Message {
+	t.Helper()
+
+	desc, err := s.findMethod("shop.v1.OrderService/ListOrders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := dynamicpb.NewMessage(desc.Input())
+	fields := desc.Input().Fields()
+	in.Set(fields.ByName("page_size"), protoreflect.ValueOfInt32(pageSize))
+	in.Set(fields.ByName("page_token"), protoreflect.ValueOfString(pageToken))
+	return in
+}
+
+// orderNames returns the names of the orders of the list response, its next page token and total size
+func orderNames(msg proto.Message) ([]string, string, int64) {
+	m := msg.ProtoReflect()
+	fields := m.Descriptor().Fields()
+
+	names := []string{}
+	list := m.Get(fields.ByName("orders")).List()
+	for i := 0; i < list.Len(); i++ {
+		order := list.Get(i).Message()
+		names = append(names, order.Get(order.Descriptor().Fields().ByName("name")).String())
+	}
+	return names, m.Get(fields.ByName("next_page_token")).String(), m.Get(fields.ByName("total_size")).Int()
+}
+
+func TestListPagination(t *testing.T) {
+	s := testServer(t, map[string]config.GRPCMethod{
+		"shop.v1.OrderService/ListOrders": {
+			ResponseObjFilePath: "orders.json",
+			Pagination:          config.Pagination{Options: map[string]any{"pageSize": float64(2)}},
+		},
+	})
+	m := s.methods["shop.v1.OrderService/ListOrders"]
+
+	tests := []struct {
+		name     string
+		pageSize int32
+		token    string
+		names    []string
+		hasNext  bool
+		code     codes.Code
+	}{
+		{"first page of the method page size", 0, "", []string{"a", "b"}, true, codes.OK},
+		{"requested page size", 3, "", []string{"a", "b", "c"}, true, codes.OK},
+		{"page of the token", 2, pagination.EncodeCursor(2), []string{"c", "d"}, true, codes.OK},
+		{"last page has no token", 2, pagination.EncodeCursor(4), []string{"e"}, false, codes.OK},
+		{"invalid token", 2, "invalid", nil, false, codes.InvalidArgument},
+		{"token past the end", 2, pagination.EncodeCursor(6), nil, false, codes.InvalidArgument},
+		{"negative page size", -1, "", nil, false, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := m.unary(listRequest(t, s, tt.pageSize, tt.token))
+			if status.Code(err) != tt.code {
+				t.Fatalf("code = %v, want %v: %v", status.Code(err), tt.code, err)
+			}
+			if err != nil {
+				return
+			}
+
+			names, next, total := orderNames(resp)
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("orders = %v, want %v", names, tt.names)
+			}
+			if (next != "") != tt.hasNext {
+				t.Errorf("next_page_token = %q, want a token %v", next, tt.hasNext)
+			}
+			if total != 5 {
+				t.Errorf("total_size = %d, want 5", total)
+			}
+		})
+	}
+}
+
+func TestListPaginationWalk(t *testing.T) {
+	s := testServer(t, map[string]config.GRPCMethod{
+		"shop.v1.OrderService/ListOrders": {ResponseObjFilePath: "orders.json"},
+	})
+	m := s.methods["shop.v1.OrderService/ListOrders"]
+
+	var all []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("the last page has a next_page_token")
+		}
+		resp, err := m.unary(listRequest(t, s, 2, token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		names, next, _ := orderNames(resp)
+		all = append(all, names...)
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(all, want) {
+		t.Errorf("orders = %v, want %v", all, want)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	s := testServer(t, map[string]config.GRPCMethod{
+		"shop.v1.OrderService/GetOrder": {Status: "NOT_FOUND", Message: "order not found"},
+	})
+
+	_, err := s.methods["shop.v1.OrderService/GetOrder"].unary(nil)
+	if st := status.Convert(err); st.Code() != codes.NotFound || st.Message() != "order not found" {
+		t.Errorf("error = %v, want NOT_FOUND order not found", err)
+	}
+}
+
+func TestInvalidMethods(t *testing.T) {
+	tests := []struct {
+		name    string
+		methods map[string]config.GRPCMethod
+	}{
+		{"unknown method", map[string]config.GRPCMethod{"shop.v1.OrderService/Missing": {ResponseObjFilePath: "order.json"}}},
+		{"unknown service", map[string]config.GRPCMethod{"shop.v1.Missing/GetOrder": {ResponseObjFilePath: "order.json"}}},
+		{"array for a unary method", map[string]config.GRPCMethod{"shop.v1.OrderService/GetOrder": {ResponseObjFilePath: "stream.json"}}},
+		{"invalid status", map[string]config.GRPCMethod{"shop.v1.OrderService/GetOrder": {Status: "NOPE"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "shop.proto"), []byte(testProto), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "order.json"), []byte(`{"name":"a"}`), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "stream.json"), []byte(`[{"name":"a"}]`), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			for name, m := range tt.methods {
+				if m.ResponseObjFilePath != "" {
+					m.ResponseObjFilePath = filepath.Join(dir, m.ResponseObjFilePath)
+				}
+				tt.methods[name] = m
+			}
+
+			_, err := NewServer(&config.GRPC{Port: "0", ProtoFiles: []string{"shop.proto"}, ImportPaths: []string{dir}, Methods: tt.methods}, nil)
+			if err == nil {
+				t.Error("NewServer() succeeded")
+			}
+		})
+	}
+}
+
+func TestServerStream(t *testing.T) {
+	s := testServer(t, map[string]config.GRPCMethod{
+		"shop.v1.OrderService/WatchOrders": {ResponseObjFilePath: "stream.json"},
+	})
+
+	var names []string
+	err := s.methods["shop.v1.OrderService/WatchOrders"].stream(context.Background(), func(msg proto.Message) error {
+		m := msg.ProtoReflect()
+		names = append(names, m.Get(m.Descriptor().Fields().ByName("name")).String())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("messages = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,270 @@
+package grpcmock
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"mock-server/internal/config"
+	"mock-server/pkg/logger"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
+	v1reflectiongrpc "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	v1alphareflectiongrpc "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var (
+	errCreateServer  = errors.New("failed to create grpc server")
+	errInvalidMethod = errors.New("invalid grpc method")
+)
+
+// Server serves the gRPC services of the descriptors from the method fixtures
+type Server struct {
+	port    string
+	files   *protoregistry.Files
+	methods map[string]*method
+	server  *grpc.Server
+}
+
+// NewServer creates the gRPC server of the given config, serving TLS when tlsConfig is set
+func NewServer(cfg *config.GRPC, tlsConfig *tls.Config) (*Server, error) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("creating grpc server", map[string]any{"port": cfg.Port})
+
+	files, err := loadDescriptors(cfg)
+	if err != nil {
+		mockLogger.Warn("invalid grpc descriptors", err)
+		return nil, errors.Join(errCreateServer, err)
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(logUnary),
+		grpc.ChainStreamInterceptor(logStream),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	s := &Server{
+		port:    cfg.Port,
+		files:   files,
+		methods: make(map[string]*method, len(cfg.Methods)),
+		server:  grpc.NewServer(opts...),
+	}
+
+	for name, m := range cfg.Methods {
+		name = strings.TrimPrefix(name, "/")
+		desc, err := s.findMethod(name)
+		if err != nil {
+			mockLogger.Warn(err.Error(), err)
+			return nil, errors.Join(errCreateServer, err)
+		}
+
+		res, err := newMethod(name, desc, m)
+		if err != nil {
+			mockLogger.Warn(err.Error(), err)
+			return nil, errors.Join(errCreateServer, err)
+		}
+		s.methods[name] = res
+	}
+
+	// Every service of the descriptors is served, the methods without a fixture are unimplemented
+	files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			s.registerService(services.Get(i))
+		}
+		return true
+	})
+
+	if cfg.Reflection {
+		options := reflection.ServerOptions{
+			Services:           s.server,
+			DescriptorResolver: files,
+		}
+		v1reflectiongrpc.RegisterServerReflectionServer(s.server, reflection.NewServerV1(options))
+		v1alphareflectiongrpc.RegisterServerReflectionServer(s.server, reflection.NewServer(options))
+	}
+
+	return s, nil
+}
+
+// loadDescriptors compiles the .proto files of the config, or loads its FileDescriptorSet
+func loadDescriptors(cfg *config.GRPC) (*protoregistry.Files, error) {
+	if cfg.DescriptorSet != "" {
+		data, err := os.ReadFile(config.ResolveFilePath(cfg.DescriptorSet))
+		if err != nil {
+			return nil, err
+		}
+
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(data, &set); err != nil {
+			return nil, err
+		}
+		return protodesc.NewFiles(&set)
+	}
+
+	importPaths := make([]string, 0, len(cfg.ImportPaths))
+	for _, path := range cfg.ImportPaths {
+		importPaths = append(importPaths, config.ResolveFilePath(path))
+	}
+	if len(importPaths) == 0 {
+		importPaths = append(importPaths, config.ResolveFilePath("."))
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+	}
+	compiled, err := compiler.Compile(context.Background(), cfg.ProtoFiles...)
+	if err != nil {
+		return nil, err
+	}
+
+	files := new(protoregistry.Files)
+	for _, file := range compiled {
+		if err := registerFile(files, file); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// registerFile registers the file after its imports, so that the reflection
+// service can serve the complete descriptors
+func registerFile(files *protoregistry.Files, file protoreflect.FileDescriptor) error {
+	if _, err := files.FindFileByPath(file.Path()); err == nil {
+		return nil
+	}
+
+	imports := file.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := registerFile(files, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+
+	return files.RegisterFile(file)
+}
+
+// findMethod returns the descriptor of the method of the full name package.Service/Method
+func (s *Server) findMethod(name string) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName, found := strings.Cut(name, "/")
+	if !found {
+		return nil, fmt.Errorf("%w: %s must be written as package.Service/Method", errInvalidMethod, name)
+	}
+
+	desc, err := s.files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("%w: service %s not found", errInvalidMethod, serviceName)
+	}
+	service, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a service", errInvalidMethod, serviceName)
+	}
+
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf("%w: method %s not found", errInvalidMethod, name)
+	}
+
+	return method, nil
+}
+
+// registerService registers the handlers of the methods of the service
+func (s *Server) registerService(service protoreflect.ServiceDescriptor) {
+	desc := grpc.ServiceDesc{
+		ServiceName: string(service.FullName()),
+		HandlerType: (*any)(nil),
+		Metadata:    service.ParentFile().Path(),
+	}
+
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+		name := fmt.Sprintf("%s/%s", service.FullName(), md.Name())
+		m := s.methods[name]
+
+		if !md.IsStreamingClient() && !md.IsStreamingServer() {
+			desc.Methods = append(desc.Methods, grpc.MethodDesc{
+				MethodName: string(md.Name()),
+				Handler:    unaryHandler(name, md, m),
+			})
+			continue
+		}
+
+		desc.Streams = append(desc.Streams, grpc.StreamDesc{
+			StreamName:    string(md.Name()),
+			Handler:       streamHandler(name, md, m),
+			ServerStreams: md.IsStreamingServer(),
+			ClientStreams: md.IsStreamingClient(),
+		})
+	}
+
+	s.server.RegisterService(&desc, struct{}{})
+}
+
+// Serve accepts the gRPC connections on the port of the server
+func (s *Server) Serve() error {
+	listener, err := net.Listen("tcp", ":"+s.port)
+	if err != nil {
+		return err
+	}
+
+	return s.server.Serve(listener)
+}
+
+// Stop stops the server gracefully, the open streams are closed when the
+// context is done first
+func (s *Server) Stop(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+}
+
+// logUnary logs the unary calls like the HTTP logger middleware
+func logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(info.FullMethod, start, err)
+	return resp, err
+}
+
+// logStream logs the streaming calls like the HTTP logger middleware
+func logStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	logCall(info.FullMethod, start, err)
+	return err
+}
+
+func logCall(method string, start time.Time, err error) {
+	logger.GetLogger().InfoW("rpc executed", map[string]any{
+		"method":  method,
+		"status":  status.Code(err).String(),
+		"latency": time.Since(start),
+	})
+}
@@ -0,0 +1,322 @@
+package job
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mock-server/internal/config"
+	"mock-server/internal/pagination"
+	"mock-server/internal/state"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idParam = "jobId"
+
+	pendingStatus  = "pending"
+	runningStatus  = "running"
+	completeStatus = "complete"
+	failedStatus   = "failed"
+
+	defaultFailureMessage = "job failed"
+
+	// maxJobs is the number of jobs kept, the oldest finished jobs are dropped first
+	maxJobs = 1000
+)
+
+var (
+	errCreateQueue = errors.New("failed to create job queue")
+)
+
+// Queue serves an asynchronous job endpoint: the requests of the endpoint
+// start jobs, whose status and result are served on their own routes
+type Queue struct {
+	method          string
+	path            string
+	statusPath      string
+	resultPath      string
+	pendingDuration time.Duration
+	runningDuration time.Duration
+	failProbability float64
+	failureMessage  string
+	statuses        map[string]string
+	result          pagination.Paginator
+
+	mu   sync.RWMutex
+	jobs map[string]*job
+	// order holds the job ids from the oldest to the newest
+	order []string
+}
+
+var _ state.Stateful = (*Queue)(nil)
+
+// job is a started job, its status follows from the time since its creation
+type job struct {
+	id        string
+	createdAt time.Time
+	fails     bool
+}
+
+// jobState is the persisted state of a job
+type jobState struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Fails     bool      `json:"fails,omitempty"`
+}
+
+// NewQueue creates the job queue of the given endpoint
+func NewQueue(endpoint config.Endpoint) (*Queue, error) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("creating job queue", map[string]any{"endpoint": endpoint.Path})
+
+	path := strings.TrimSuffix(endpoint.Path, "/")
+	cfg := endpoint.Job
+	if cfg == nil {
+		cfg = &config.Job{}
+	}
+
+	q := &Queue{
+		method:          endpoint.Method,
+		path:            endpoint.Path,
+		statusPath:      path + "/:" + idParam,
+		resultPath:      path + "/:" + idParam + "/result",
+		failProbability: cfg.FailProbability,
+		failureMessage:  defaultFailureMessage,
+		statuses:        cfg.Statuses,
+		jobs:            make(map[string]*job),
+	}
+
+	if cfg.StatusPath != "" {
+		q.statusPath = cfg.StatusPath
+	}
+	if cfg.ResultPath != "" {
+		q.resultPath = cfg.ResultPath
+	}
+	if cfg.FailureMessage != "" {
+		q.failureMessage = cfg.FailureMessage
+	}
+	if cfg.PendingDuration != "" {
+		q.pendingDuration, _ = time.ParseDuration(cfg.PendingDuration)
+	}
+	if cfg.RunningDuration != "" {
+		q.runningDuration, _ = time.ParseDuration(cfg.RunningDuration)
+	}
+
+	// The result is the dataset of the endpoint, paginated or exported as configured
+	resultEndpoint := endpoint
+	resultEndpoint.Type = ""
+	resultEndpoint.Job = nil
+	resultEndpoint.Path = q.resultPath
+	resultEndpoint.Method = http.MethodGet
+
+	var err error
+	q.result, err = pagination.CreatePaginator(resultEndpoint)
+	if err != nil {
+		mockLogger.Warn("failed to create job result", err)
+		return nil, errors.Join(errCreateQueue, err)
+	}
+
+	return q, nil
+}
+
+// Routes returns the routes starting a job and reporting its status and result
+func (q *Queue) Routes() gin.RoutesInfo {
+	return gin.RoutesInfo{
+		{Method: q.method, Path: q.path, HandlerFunc: q.Start},
+		{Method: http.MethodGet, Path: q.statusPath, HandlerFunc: q.Status},
+		{Method: http.MethodGet, Path: q.resultPath, HandlerFunc: q.Result},
+	}
+}
+
+// Start is the handler function starting a job
+func (q *Queue) Start(c *gin.Context) {
+	j := &job{
+		id:        newID(),
+		createdAt: time.Now(),
+		fails:     q.failProbability > 0 && mathrand.Float64() < q.failProbability,
+	}
+
+	q.mu.Lock()
+	q.add(j)
+	q.mu.Unlock()
+
+	c.Header("Location", q.link(c, q.statusPath, j.id))
+	q.respond(c, http.StatusAccepted, j)
+}
+
+// Status is the handler function reporting a job. Until the job is done the
+// Retry-After header tells when the status changes next.
+func (q *Queue) Status(c *gin.Context) {
+	j, found := q.job(c)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+
+	q.respond(c, http.StatusOK, j)
+}
+
+// Result is the handler function serving the result of a complete job
+func (q *Queue) Result(c *gin.Context) {
+	j, found := q.job(c)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+
+	if status, _ := q.status(j); status != completeStatus {
+		c.JSON(http.StatusConflict, gin.H{"error": "job is not complete", "status": q.rename(status)})
+		return
+	}
+
+	q.result.Paginate(c)
+}
+
+// respond writes the status of the job
+func (q *Queue) respond(c *gin.Context, code int, j *job) {
+	status, remaining := q.status(j)
+
+	body := gin.H{
+		"id":        j.id,
+		"status":    q.rename(status),
+		"progress":  q.progress(j),
+		"createdAt": j.createdAt.UTC().Format(time.RFC3339),
+		"statusUrl": q.link(c, q.statusPath, j.id),
+	}
+
+	switch status {
+	case completeStatus:
+		body["resultUrl"] = q.link(c, q.resultPath, j.id)
+	case failedStatus:
+		body["error"] = q.failureMessage
+	default:
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	}
+
+	c.JSON(code, body)
+}
+
+// status returns the status of the job and the time until it changes
+func (q *Queue) status(j *job) (string, time.Duration) {
+	elapsed := time.Since(j.createdAt)
+
+	switch {
+	case elapsed < q.pendingDuration:
+		return pendingStatus, q.pendingDuration - elapsed
+	case elapsed < q.pendingDuration+q.runningDuration:
+		return runningStatus, q.pendingDuration + q.runningDuration - elapsed
+	case j.fails:
+		return failedStatus, 0
+	default:
+		return completeStatus, 0
+	}
+}
+
+// progress returns the percentage of the running time the job has run
+func (q *Queue) progress(j *job) int {
+	elapsed := time.Since(j.createdAt) - q.pendingDuration
+	switch {
+	case elapsed <= 0:
+		return 0
+	case elapsed >= q.runningDuration:
+		return 100
+	default:
+		return int(elapsed * 100 / q.runningDuration)
+	}
+}
+
+// rename returns the configured name of the status
+func (q *Queue) rename(status string) string {
+	if name, ok := q.statuses[status]; ok {
+		return name
+	}
+	return status
+}
+
+// add adds the job, dropping the oldest finished job, or else the oldest job,
+// once more than maxJobs jobs exist
+func (q *Queue) add(j *job) {
+	q.jobs[j.id] = j
+	q.order = append(q.order, j.id)
+
+	if len(q.order) <= maxJobs {
+		return
+	}
+
+	drop := 0
+	for i, id := range q.order {
+		if status, _ := q.status(q.jobs[id]); status == completeStatus || status == failedStatus {
+			drop = i
+			break
+		}
+	}
+	delete(q.jobs, q.order[drop])
+	q.order = append(q.order[:drop], q.order[drop+1:]...)
+}
+
+// Snapshot returns the started jobs
+func (q *Queue) Snapshot() (json.RawMessage, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	jobs := make([]jobState, 0, len(q.order))
+	for _, id := range q.order {
+		j := q.jobs[id]
+		jobs = append(jobs, jobState{ID: j.id, CreatedAt: j.createdAt, Fails: j.fails})
+	}
+
+	return json.Marshal(jobs)
+}
+
+// Decode decodes the started jobs and returns the function replacing the jobs
+// with them, their status follows from their creation time
+func (q *Queue) Decode(data json.RawMessage) (func(), error) {
+	var jobs []jobState
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		q.jobs = make(map[string]*job, len(jobs))
+		q.order = nil
+		for _, s := range jobs {
+			q.add(&job{id: s.ID, createdAt: s.CreatedAt, fails: s.Fails})
+		}
+	}, nil
+}
+
+// job returns the job of the request
+func (q *Queue) job(c *gin.Context) (*job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	j, found := q.jobs[c.Param(idParam)]
+	return j, found
+}
+
+// link returns the absolute URL of the route of the job
+func (q *Queue) link(c *gin.Context, path, id string) string {
+	return pagination.BaseURL(c) + strings.Replace(path, ":"+idParam, id, 1)
+}
+
+// newID returns a random job id
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,235 @@
+package job
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mock-server/internal/config"
+	"mock-server/internal/state"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testQueue returns the queue of an export endpoint whose result is two records
+func testQueue(t *testing.T, cfg *config.Job) *Queue {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "result.json")
+	if err := os.WriteFile(path, []byte(`{"data":[{"id":1},{"id":2}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := NewQueue(config.Endpoint{
+		Path:                "/exports",
+		Method:              http.MethodPost,
+		Type:                "job",
+		Job:                 cfg,
+		ResponseObjFilePath: path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// send sends the request to the registered queue and returns the response
+func send(t *testing.T, q *Queue, method, target string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+
+	engine := gin.New()
+	for _, route := range q.Routes() {
+		engine.Handle(route.Method, route.Path, route.HandlerFunc)
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+	}
+	return w, body
+}
+
+// start starts a job and returns its id
+func start(t *testing.T, q *Queue) string {
+	t.Helper()
+
+	w, body := send(t, q, http.MethodPost, "/exports")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("start status = %d, want %d", w.Code, http.StatusAccepted)
+	}
+	id, _ := body["id"].(string)
+	if loc := w.Header().Get("Location"); !strings.HasSuffix(loc, strings.Replace(q.statusPath, ":"+idParam, id, 1)) {
+		t.Errorf("Location = %q, want the status of job %q", loc, id)
+	}
+	return id
+}
+
+func TestLifecycle(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          *config.Job
+		status       string
+		retryAfter   string
+		resultStatus int
+	}{
+		{"pending", &config.Job{PendingDuration: "1h"}, pendingStatus, "3600", http.StatusConflict},
+		{"running", &config.Job{RunningDuration: "1h"}, runningStatus, "3600", http.StatusConflict},
+		{"complete", nil, completeStatus, "", http.StatusOK},
+		{"failed", &config.Job{FailProbability: 1}, failedStatus, "", http.StatusConflict},
+		{"renamed", &config.Job{Statuses: map[string]string{completeStatus: "SUCCEEDED"}}, "SUCCEEDED", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := testQueue(t, tt.cfg)
+			id := start(t, q)
+
+			w, body := send(t, q, http.MethodGet, "/exports/"+id)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code = %d, want %d", w.Code, http.StatusOK)
+			}
+			if body["status"] != tt.status {
+				t.Errorf("status = %v, want %s", body["status"], tt.status)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retryAfter)
+			}
+			if tt.status == failedStatus && body["error"] != defaultFailureMessage {
+				t.Errorf("error = %v, want %q", body["error"], defaultFailureMessage)
+			}
+
+			w, body = send(t, q, http.MethodGet, "/exports/"+id+"/result")
+			if w.Code != tt.resultStatus {
+				t.Fatalf("result status code = %d, want %d: %v", w.Code, tt.resultStatus, body)
+			}
+			if w.Code == http.StatusOK {
+				if records, _ := body["data"].([]any); len(records) != 2 {
+					t.Errorf("result = %v, want two records", body)
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownJob(t *testing.T) {
+	q := testQueue(t, nil)
+
+	for _, target := range []string{"/exports/missing", "/exports/missing/result"} {
+		if w, _ := send(t, q, http.MethodGet, target); w.Code != http.StatusNotFound {
+			t.Errorf("%s status code = %d, want %d", target, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestCustomPaths(t *testing.T) {
+	q := testQueue(t, &config.Job{StatusPath: "/jobs/:jobId", ResultPath: "/jobs/:jobId/download"})
+	id := start(t, q)
+
+	_, body := send(t, q, http.MethodGet, "/jobs/"+id)
+	if url, _ := body["resultUrl"].(string); !strings.HasSuffix(url, "/jobs/"+id+"/download") {
+		t.Errorf("resultUrl = %q", url)
+	}
+	if w, _ := send(t, q, http.MethodGet, "/jobs/"+id+"/download"); w.Code != http.StatusOK {
+		t.Errorf("result status code = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestStatusAndProgress(t *testing.T) {
+	q := testQueue(t, &config.Job{PendingDuration: "10s", RunningDuration: "20s"})
+
+	tests := []struct {
+		elapsed  time.Duration
+		status   string
+		progress int
+	}{
+		{0, pendingStatus, 0},
+		{5 * time.Second, pendingStatus, 0},
+		{15 * time.Second, runningStatus, 25},
+		{25 * time.Second, runningStatus, 75},
+		{time.Minute, completeStatus, 100},
+	}
+
+	for _, tt := range tests {
+		j := &job{createdAt: time.Now().Add(-tt.elapsed)}
+		if status, _ := q.status(j); status != tt.status {
+			t.Errorf("status after %v = %s, want %s", tt.elapsed, status, tt.status)
+		}
+		if progress := q.progress(j); progress != tt.progress {
+			t.Errorf("progress after %v = %d, want %d", tt.elapsed, progress, tt.progress)
+		}
+	}
+}
+
+func TestMaxJobs(t *testing.T) {
+	tests := []struct {
+		name     string
+		finished int
+		dropped  string
+	}{
+		{"oldest finished job dropped", 10, "job-10"},
+		{"oldest job dropped when none finished", -1, "job-0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := testQueue(t, &config.Job{PendingDuration: "1h"})
+
+			for i := 0; i <= maxJobs; i++ {
+				j := &job{id: "job-" + strconv.Itoa(i), createdAt: time.Now()}
+				if i == tt.finished {
+					j.createdAt = time.Now().Add(-2 * time.Hour)
+				}
+				q.add(j)
+			}
+
+			if len(q.jobs) != maxJobs || len(q.order) != maxJobs {
+				t.Fatalf("jobs = %d, order = %d, want %d", len(q.jobs), len(q.order), maxJobs)
+			}
+			if _, found := q.jobs[tt.dropped]; found {
+				t.Errorf("%s kept", tt.dropped)
+			}
+			if last := q.order[len(q.order)-1]; last != "job-"+strconv.Itoa(maxJobs) {
+				t.Errorf("newest job = %s", last)
+			}
+		})
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	q := testQueue(t, &config.Job{FailProbability: 1})
+	first := start(t, q)
+	second := start(t, q)
+
+	data, err := q.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := testQueue(t, nil)
+	if err := state.Restore(restored, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(restored.order) != 2 || restored.order[0] != first || restored.order[1] != second {
+		t.Fatalf("restored order = %v, want [%s %s]", restored.order, first, second)
+	}
+	if j := restored.jobs[first]; !j.fails || !j.createdAt.Equal(q.jobs[first].createdAt) {
+		t.Errorf("restored job = %+v, want %+v", j, q.jobs[first])
+	}
+	if err := state.Restore(restored, json.RawMessage(`{`)); err == nil {
+		t.Error("Restore() of invalid data succeeded")
+	}
+}
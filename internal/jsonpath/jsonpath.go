@@ -0,0 +1,161 @@
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	errInvalidPath = errors.New("invalid json path")
+)
+
+// segment is a single step of a path, either an object key or an array index
+type segment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// Path is a parsed JSON path. It supports the dotted notation (data.items)
+// and the basic JSONPath notation ($.data.items[0], $['data']['items']).
+type Path struct {
+	raw      string
+	segments []segment
+}
+
+// Parse parses the given path
+func Parse(raw string) (Path, error) {
+	p := Path{raw: raw}
+
+	rest := strings.TrimSpace(raw)
+	rest = strings.TrimPrefix(rest, "$")
+	rest = strings.TrimPrefix(rest, ".")
+
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return Path{}, fmt.Errorf("%w: %s", errInvalidPath, raw)
+			}
+			inner := rest[1:end]
+			rest = strings.TrimPrefix(rest[end+1:], ".")
+
+			if quoted := strings.Trim(inner, `'"`); quoted != inner {
+				p.segments = append(p.segments, segment{key: quoted})
+				continue
+			}
+
+			index, err := strconv.Atoi(inner)
+			if err != nil {
+				return Path{}, fmt.Errorf("%w: %s", errInvalidPath, raw)
+			}
+			p.segments = append(p.segments, segment{index: index, isIndex: true})
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" {
+				return Path{}, fmt.Errorf("%w: %s", errInvalidPath, raw)
+			}
+			p.segments = append(p.segments, segment{key: key})
+			rest = strings.TrimPrefix(rest[end:], ".")
+		}
+	}
+
+	return p, nil
+}
+
+// String returns the path as it was written
+func (p Path) String() string {
+	return p.raw
+}
+
+// IsRoot tells whether the path selects the whole document, like $
+func (p Path) IsRoot() bool {
+	return len(p.segments) == 0
+}
+
+// Get returns the value found at the path in the given object
+func (p Path) Get(obj any) (any, bool) {
+	current := obj
+
+	for _, s := range p.segments {
+		if s.isIndex {
+			arr, ok := current.([]any)
+			if !ok || s.index < 0 || s.index >= len(arr) {
+				return nil, false
+			}
+			current = arr[s.index]
+			continue
+		}
+
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[s.key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// Get parses the path and returns the value found at it in the given object
+func Get(obj any, raw string) (any, bool) {
+	p, err := Parse(raw)
+	if err != nil {
+		return nil, false
+	}
+	return p.Get(obj)
+}
+
+// Set sets the value at the path in the given object, the missing objects
+// on the way are created. Array indexes must exist. The root path can not be set.
+func (p Path) Set(obj any, value any) error {
+	if len(p.segments) == 0 {
+		return fmt.Errorf("%w: %s", errInvalidPath, p.raw)
+	}
+
+	current := obj
+	for i, s := range p.segments {
+		last := i == len(p.segments)-1
+
+		if s.isIndex {
+			arr, ok := current.([]any)
+			if !ok || s.index < 0 || s.index >= len(arr) {
+				return fmt.Errorf("%w: %s", errInvalidPath, p.raw)
+			}
+			if last {
+				arr[s.index] = value
+				return nil
+			}
+			current = arr[s.index]
+			continue
+		}
+
+		m, ok := current.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%w: %s", errInvalidPath, p.raw)
+		}
+		if last {
+			m[s.key] = value
+			return nil
+		}
+
+		next, ok := m[s.key]
+		if !ok || next == nil {
+			next = map[string]any{}
+			m[s.key] = next
+		}
+		current = next
+	}
+
+	return nil
+}
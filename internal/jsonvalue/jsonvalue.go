@@ -0,0 +1,29 @@
+package jsonvalue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// String formats a decoded JSON value as text, e.g. for a header, a CSV cell
+// or a comparison. JSON numbers are decoded as float64, which fmt formats with
+// an exponent from 1e+06 on, so they are formatted without one. Objects and
+// arrays are formatted as JSON and null as the empty string.
+func String(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case map[string]any, []any:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
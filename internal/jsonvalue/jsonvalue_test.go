@@ -0,0 +1,30 @@
+package jsonvalue
+
+import "testing"
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"null", nil, ""},
+		{"string", "abc", "abc"},
+		{"small number", float64(42), "42"},
+		{"large number", float64(1000000), "1000000"},
+		{"larger number", float64(123456789012), "123456789012"},
+		{"fraction", 1.5, "1.5"},
+		{"bool", false, "false"},
+		{"object", map[string]any{"a": float64(1)}, `{"a":1}`},
+		{"array", []any{"a", float64(2e6)}, `["a",2000000]`},
+		{"int", 7, "7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := String(tt.value); got != tt.want {
+				t.Errorf("String(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
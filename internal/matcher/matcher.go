@@ -0,0 +1,197 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"mock-server/internal/config"
+	"mock-server/internal/jsonpath"
+	"mock-server/internal/jsonvalue"
+
+	"github.com/gin-gonic/gin"
+)
+
+type operator string
+
+type location string
+
+const (
+	equals   operator = "equals"
+	contains operator = "contains"
+	regex    operator = "regex"
+	exists   operator = "exists"
+
+	query  location = "query"
+	header location = "header"
+	body   location = "body"
+
+	// requestBodyKey caches the parsed request body in the gin context
+	requestBodyKey = "matcher.requestBody"
+)
+
+var (
+	errInvalidOperator = errors.New("invalid matcher operator")
+)
+
+// Matcher matches a single value of the request
+type Matcher struct {
+	location location
+	key      string
+	operator operator
+	value    string
+	path     jsonpath.Path
+	pattern  *regexp.Regexp
+}
+
+// NewMatchers creates the matchers for the given matcher configs
+func NewMatchers(cfgs []config.Matcher) ([]Matcher, error) {
+	matchers := make([]Matcher, 0, len(cfgs))
+
+	for _, cfg := range cfgs {
+		m := Matcher{
+			location: location(cfg.Location),
+			key:      cfg.Key,
+			operator: operator(cfg.Operator),
+			value:    cfg.Value,
+		}
+
+		if m.operator == "" {
+			m.operator = equals
+		}
+
+		switch m.operator {
+		case equals, contains, exists:
+		case regex:
+			pattern, err := regexp.Compile(cfg.Value)
+			if err != nil {
+				return nil, errors.Join(fmt.Errorf("invalid matcher regex: %s", cfg.Value), err)
+			}
+			m.pattern = pattern
+		default:
+			return nil, fmt.Errorf("%w: %s", errInvalidOperator, cfg.Operator)
+		}
+
+		if m.location == body {
+			path, err := jsonpath.Parse(cfg.Key)
+			if err != nil {
+				return nil, err
+			}
+			m.path = path
+		}
+
+		matchers = append(matchers, m)
+	}
+
+	return matchers, nil
+}
+
+// MatchAll reports whether the request matches all the given matchers
+func MatchAll(c *gin.Context, matchers []Matcher) bool {
+	var (
+		requestBody any
+		bodyRead    bool
+	)
+
+	for _, m := range matchers {
+		if m.location == body && !bodyRead {
+			requestBody = readBody(c)
+			bodyRead = true
+		}
+		if !m.match(c, requestBody) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchMessage reports whether the message, e.g. a websocket message, matches all
+// the given body matchers. A message that is not JSON is matched as a string by
+// the key $.
+func MatchMessage(message []byte, matchers []Matcher) bool {
+	var messageBody any
+	if err := json.Unmarshal(message, &messageBody); err != nil {
+		messageBody = string(message)
+	}
+
+	for _, m := range matchers {
+		if m.location != body || !m.match(nil, messageBody) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// match reports whether the request matches the matcher
+func (m Matcher) match(c *gin.Context, requestBody any) bool {
+	var (
+		actual string
+		found  bool
+	)
+
+	switch m.location {
+	case query:
+		actual, found = c.GetQuery(m.key)
+	case header:
+		actual = c.GetHeader(m.key)
+		found = actual != ""
+	case body:
+		var value any
+		value, found = m.path.Get(requestBody)
+		actual = stringify(value)
+	}
+
+	if !found {
+		return false
+	}
+
+	switch m.operator {
+	case exists:
+		return true
+	case contains:
+		return strings.Contains(actual, m.value)
+	case regex:
+		return m.pattern.MatchString(actual)
+	default:
+		return actual == m.value
+	}
+}
+
+// readBody reads the JSON request body and restores it for the handler
+func readBody(c *gin.Context) any {
+	if requestBody, ok := c.Get(requestBodyKey); ok {
+		return requestBody
+	}
+
+	if c.Request.Body == nil {
+		return nil
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(data))
+
+	var requestBody any
+	if err := json.Unmarshal(data, &requestBody); err != nil {
+		requestBody = nil
+	}
+	c.Set(requestBodyKey, requestBody)
+
+	return requestBody
+}
+
+// stringify converts a JSON value to the string it is compared with
+func stringify(value any) string {
+	if value == nil {
+		return "null"
+	}
+	return jsonvalue.String(value)
+}
@@ -0,0 +1,140 @@
+package matcher
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mock-server/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testContext returns the context of a POST request with the JSON body
+func testContext(target, body string, header map[string]string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(body))
+	for k, v := range header {
+		c.Request.Header.Set(k, v)
+	}
+	return c
+}
+
+func TestOperators(t *testing.T) {
+	const (
+		target = "/search?status=open&tag="
+		body   = `{"user":{"id":1000000,"name":"Ann Lee"},"tags":["a","b"],"deleted":null,"active":true}`
+	)
+	header := map[string]string{"X-Tenant": "acme-eu"}
+
+	tests := []struct {
+		name string
+		cfg  config.Matcher
+		want bool
+	}{
+		{"query equals", config.Matcher{Location: "query", Key: "status", Value: "open"}, true},
+		{"query equals other value", config.Matcher{Location: "query", Key: "status", Value: "closed"}, false},
+		{"query exists", config.Matcher{Location: "query", Key: "status", Operator: "exists"}, true},
+		{"empty query exists", config.Matcher{Location: "query", Key: "tag", Operator: "exists"}, true},
+		{"missing query", config.Matcher{Location: "query", Key: "page", Operator: "exists"}, false},
+		{"header equals", config.Matcher{Location: "header", Key: "x-tenant", Value: "acme-eu"}, true},
+		{"header contains", config.Matcher{Location: "header", Key: "X-Tenant", Operator: "contains", Value: "acme"}, true},
+		{"header regex", config.Matcher{Location: "header", Key: "X-Tenant", Operator: "regex", Value: `^acme-(eu|us)$`}, true},
+		{"header regex not matched", config.Matcher{Location: "header", Key: "X-Tenant", Operator: "regex", Value: `^acme-us$`}, false},
+		{"missing header", config.Matcher{Location: "header", Key: "X-Region", Operator: "exists"}, false},
+		{"body field", config.Matcher{Location: "body", Key: "user.name", Value: "Ann Lee"}, true},
+		{"body large number", config.Matcher{Location: "body", Key: "$.user.id", Value: "1000000"}, true},
+		{"body bool", config.Matcher{Location: "body", Key: "active", Value: "true"}, true},
+		{"body null", config.Matcher{Location: "body", Key: "deleted", Value: "null"}, true},
+		{"body array", config.Matcher{Location: "body", Key: "tags", Value: `["a","b"]`}, true},
+		{"body array item", config.Matcher{Location: "body", Key: "tags[1]", Value: "b"}, true},
+		{"body contains", config.Matcher{Location: "body", Key: "user.name", Operator: "contains", Value: "Lee"}, true},
+		{"body regex", config.Matcher{Location: "body", Key: "user.id", Operator: "regex", Value: `^\d{7}$`}, true},
+		{"body exists", config.Matcher{Location: "body", Key: "user", Operator: "exists"}, true},
+		{"missing body field", config.Matcher{Location: "body", Key: "user.email", Operator: "exists"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchers, err := NewMatchers([]config.Matcher{tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := MatchAll(testContext(target, body, header), matchers); got != tt.want {
+				t.Errorf("MatchAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchAll(t *testing.T) {
+	matchers, err := NewMatchers([]config.Matcher{
+		{Location: "query", Key: "status", Value: "open"},
+		{Location: "body", Key: "type", Value: "alert"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !MatchAll(testContext("/?status=open", `{"type":"alert"}`, nil), matchers) {
+		t.Error("request matching all the matchers not matched")
+	}
+	if MatchAll(testContext("/?status=open", `{"type":"event"}`, nil), matchers) {
+		t.Error("request matching one of the matchers matched")
+	}
+	if MatchAll(testContext("/?status=open", `not json`, nil), matchers) {
+		t.Error("body which is not JSON matched")
+	}
+	if !MatchAll(testContext("/", "", nil), nil) {
+		t.Error("no matchers do not match every request")
+	}
+
+	// The handler still reads the body the matchers have read
+	c := testContext("/?status=open", `{"type":"alert"}`, nil)
+	MatchAll(c, matchers)
+	if data, _ := io.ReadAll(c.Request.Body); string(data) != `{"type":"alert"}` {
+		t.Errorf("body after matching = %q", data)
+	}
+}
+
+func TestMatchMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Matcher
+		message string
+		want    bool
+	}{
+		{"json field", config.Matcher{Location: "body", Key: "type", Value: "ping"}, `{"type":"ping"}`, true},
+		{"other json field", config.Matcher{Location: "body", Key: "type", Value: "ping"}, `{"type":"pong"}`, false},
+		{"text message", config.Matcher{Location: "body", Key: "$", Value: "ping"}, `ping`, true},
+		{"not a body matcher", config.Matcher{Location: "query", Key: "type", Value: "ping"}, `{"type":"ping"}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchers, err := NewMatchers([]config.Matcher{tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := MatchMessage([]byte(tt.message), matchers); got != tt.want {
+				t.Errorf("MatchMessage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMatchersInvalid(t *testing.T) {
+	if _, err := NewMatchers([]config.Matcher{{Location: "query", Key: "q", Operator: "startsWith"}}); !errors.Is(err, errInvalidOperator) {
+		t.Errorf("NewMatchers() error = %v, want %v", err, errInvalidOperator)
+	}
+	if _, err := NewMatchers([]config.Matcher{{Location: "query", Key: "q", Operator: "regex", Value: "("}}); err == nil {
+		t.Error("NewMatchers() of an invalid regex succeeded")
+	}
+}
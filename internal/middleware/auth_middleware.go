@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"mock-server/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultAPIKeyHeader = "X-API-Key"
+
+// AuthMiddleware rejects the requests without the credentials of the auth config
+func AuthMiddleware(auth *config.Auth) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authorized(c, auth) {
+			c.Next()
+			return
+		}
+
+		switch auth.Type {
+		case "bearer":
+			c.Header("WWW-Authenticate", "Bearer")
+		case "basic":
+			c.Header("WWW-Authenticate", `Basic realm="mock-server"`)
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	}
+}
+
+// authorized reports whether the request carries valid credentials
+func authorized(c *gin.Context, auth *config.Auth) bool {
+	switch auth.Type {
+	case "bearer":
+		token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		return found && oneOf(token, auth.Tokens)
+	case "apiKey":
+		if auth.Query != "" {
+			return oneOf(c.Query(auth.Query), auth.Tokens)
+		}
+		header := auth.Header
+		if header == "" {
+			header = defaultAPIKeyHeader
+		}
+		return oneOf(c.GetHeader(header), auth.Tokens)
+	case "basic":
+		username, password, ok := c.Request.BasicAuth()
+		return ok && equal(username, auth.Username) && equal(password, auth.Password)
+	default:
+		return false
+	}
+}
+
+// oneOf reports whether the value is one of the tokens
+func oneOf(value string, tokens []string) bool {
+	for _, token := range tokens {
+		if equal(value, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares the credentials in constant time
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
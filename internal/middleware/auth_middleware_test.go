@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mock-server/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	bearer := &config.Auth{Type: "bearer", Tokens: []string{"token-a", "token-b"}}
+	apiKey := &config.Auth{Type: "apiKey", Tokens: []string{"key-a"}}
+	apiKeyHeader := &config.Auth{Type: "apiKey", Header: "X-Vendor-Key", Tokens: []string{"key-a"}}
+	apiKeyQuery := &config.Auth{Type: "apiKey", Query: "api_key", Tokens: []string{"key-a"}}
+	basic := &config.Auth{Type: "basic", Username: "user", Password: "secret"}
+
+	tests := []struct {
+		name         string
+		auth         *config.Auth
+		target       string
+		header       map[string]string
+		basicAuth    []string
+		status       int
+		authenticate string
+	}{
+		{"bearer token", bearer, "/", map[string]string{"Authorization": "Bearer token-b"}, nil, http.StatusOK, ""},
+		{"bearer unknown token", bearer, "/", map[string]string{"Authorization": "Bearer token-c"}, nil, http.StatusUnauthorized, "Bearer"},
+		{"bearer without scheme", bearer, "/", map[string]string{"Authorization": "token-a"}, nil, http.StatusUnauthorized, "Bearer"},
+		{"bearer missing", bearer, "/", nil, nil, http.StatusUnauthorized, "Bearer"},
+		{"api key in the default header", apiKey, "/", map[string]string{"X-API-Key": "key-a"}, nil, http.StatusOK, ""},
+		{"api key missing", apiKey, "/", nil, nil, http.StatusUnauthorized, ""},
+		{"api key in the configured header", apiKeyHeader, "/", map[string]string{"X-Vendor-Key": "key-a"}, nil, http.StatusOK, ""},
+		{"api key in the default header when another is configured", apiKeyHeader, "/", map[string]string{"X-API-Key": "key-a"}, nil, http.StatusUnauthorized, ""},
+		{"api key in the query", apiKeyQuery, "/?api_key=key-a", nil, nil, http.StatusOK, ""},
+		{"wrong api key in the query", apiKeyQuery, "/?api_key=key-b", nil, nil, http.StatusUnauthorized, ""},
+		{"basic", basic, "/", nil, []string{"user", "secret"}, http.StatusOK, ""},
+		{"basic wrong password", basic, "/", nil, []string{"user", "guess"}, http.StatusUnauthorized, `Basic realm="mock-server"`},
+		{"basic missing", basic, "/", nil, nil, http.StatusUnauthorized, `Basic realm="mock-server"`},
+		{"unknown type", &config.Auth{Type: "digest"}, "/", nil, nil, http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.Use(AuthMiddleware(tt.auth))
+			engine.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			if tt.basicAuth != nil {
+				req.SetBasicAuth(tt.basicAuth[0], tt.basicAuth[1])
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.authenticate {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.authenticate)
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ func LoggerMiddleware() gin.HandlerFunc {
 			"user_id":    c.GetHeader("X-User-ID"),
 			"status":     c.Writer.Status(),
 			"method":     c.Request.Method,
+			"protocol":   c.Request.Proto,
 			"path":       path,
 			"query":      query,
 			"ip":         c.ClientIP(),
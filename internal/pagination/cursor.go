@@ -0,0 +1,41 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	cursorPrefix = "offset:"
+)
+
+var (
+	errInvalidCursor = errors.New("invalid pagination cursor")
+)
+
+// EncodeCursor encodes the offset of the next record into an opaque cursor
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// DecodeCursor decodes the offset from a cursor created by EncodeCursor
+func DecodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.Join(errInvalidCursor, err)
+	}
+
+	value, found := strings.CutPrefix(string(data), cursorPrefix)
+	if !found {
+		return 0, errInvalidCursor
+	}
+
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, errInvalidCursor
+	}
+
+	return offset, nil
+}
@@ -0,0 +1,32 @@
+package pagination
+
+import "testing"
+
+func TestCursor(t *testing.T) {
+	for _, offset := range []int{0, 1, 99, 1000000} {
+		got, err := DecodeCursor(EncodeCursor(offset))
+		if err != nil || got != offset {
+			t.Errorf("DecodeCursor(EncodeCursor(%d)) = %d, %v", offset, got, err)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "%%%"},
+		{"no prefix", "MTI"},
+		{"not a number", "b2Zmc2V0Ong"},
+		{"negative", "b2Zmc2V0Oi0x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.cursor); err == nil {
+				t.Errorf("DecodeCursor(%q) succeeded", tt.cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/jsonpath"
+	"mock-server/internal/jsonvalue"
+	"mock-server/internal/template"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type exportFormat string
+
+const (
+	csvFormat    exportFormat = "csv"
+	ndjsonFormat exportFormat = "ndjson"
+
+	tokenChunking = "token"
+	rangeChunking = "range"
+
+	defaultChunkTokenKey    = "chunk"
+	defaultChunkTokenHeader = "X-Next-Chunk"
+)
+
+// exportColumn is a CSV column with the path of its value in the records
+type exportColumn struct {
+	name string
+	path jsonpath.Path
+}
+
+// exportPaginator renders the endpoint dataset as a CSV or NDJSON file
+type exportPaginator struct {
+	format      exportFormat
+	columns     []exportColumn
+	delimiter   rune
+	noHeader    bool
+	fileName    string
+	chunking    string
+	chunkSize   int
+	tokenKey    string
+	tokenHeader string
+	stream      bool
+	streamDelay time.Duration
+	records     recordSet
+	createdAt   time.Time
+}
+
+var _ Paginator = (*exportPaginator)(nil)
+
+// createExportPaginator creates a new export paginator for the given endpoint
+func createExportPaginator(endpoint config.Endpoint, source dataset.Source) (*exportPaginator, error) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("creating export paginator", map[string]any{"endpoint": endpoint.Path})
+
+	export := endpoint.Export
+	e := exportPaginator{
+		format:      exportFormat(export.Format),
+		delimiter:   ',',
+		noHeader:    export.NoHeader,
+		fileName:    export.FileName,
+		chunking:    export.Chunking,
+		chunkSize:   export.ChunkSize,
+		tokenKey:    defaultChunkTokenKey,
+		tokenHeader: defaultChunkTokenHeader,
+		stream:      export.Stream,
+		createdAt:   time.Now(),
+	}
+
+	if export.Delimiter != "" {
+		e.delimiter = []rune(export.Delimiter)[0]
+	}
+	if export.TokenKey != "" {
+		e.tokenKey = export.TokenKey
+	}
+	if export.TokenHeader != "" {
+		e.tokenHeader = export.TokenHeader
+	}
+	if export.StreamDelay != "" {
+		e.streamDelay, _ = time.ParseDuration(export.StreamDelay)
+	}
+
+	for _, column := range export.Columns {
+		field := column.Field
+		if field == "" {
+			field = column.Name
+		}
+		path, err := jsonpath.Parse(field)
+		if err != nil {
+			mockLogger.Warn("invalid export column", err)
+			return nil, err
+		}
+		e.columns = append(e.columns, exportColumn{name: column.Name, path: path})
+	}
+
+	responseObj, err := loadResponseObj(endpoint.ResponseObjFilePath)
+	if err != nil {
+		errInvalidResponse := fmt.Errorf("invalid response file path for endpoint: %s", endpoint.Path)
+		mockLogger.Warn(errInvalidResponse.Error(), err)
+		return nil, errors.Join(errInvalidResponse, err)
+	}
+
+	responseField, err := findResponseField(endpoint, responseObj)
+	if err != nil {
+		mockLogger.Warn(err.Error(), err)
+		return nil, err
+	}
+
+	e.records = loadRecordSet(endpoint, source, responseObj, responseField, loadPaginationParameters(endpoint), e.tokenKey)
+
+	return &e, nil
+}
+
+// Paginate is the handler function for the export paginator
+func (e *exportPaginator) Paginate(c *gin.Context) {
+	allRecords, err := e.records.load(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records := allRecords
+
+	// A chunk token selects the chunk of records, the next chunk token is sent in a header
+	if e.chunking == tokenChunking && e.chunkSize > 0 {
+		start := 0
+		if v := c.Query(e.tokenKey); v != "" {
+			start, err = DecodeCursor(v)
+			if err != nil || start >= len(allRecords) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chunk token"})
+				return
+			}
+		}
+
+		records = dataset.Window(allRecords, start, e.chunkSize)
+		if next := start + len(records); next < len(allRecords) {
+			c.Header(e.tokenHeader, EncodeCursor(next))
+		}
+	}
+
+	records = template.Render(e.records.project(c, records), c).([]any)
+
+	if e.fileName != "" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.fileName))
+	}
+
+	// Byte ranges are served from the complete file
+	if e.chunking == rangeChunking {
+		var buf bytes.Buffer
+		if err := e.write(&buf, records); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export file"})
+			return
+		}
+		c.Header("Content-Type", e.contentType())
+		http.ServeContent(c.Writer, c.Request, e.fileName, e.createdAt, bytes.NewReader(buf.Bytes()))
+		return
+	}
+
+	if e.stream {
+		e.writeStream(c, records)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := e.write(&buf, records); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export file"})
+		return
+	}
+	c.Data(http.StatusOK, e.contentType(), buf.Bytes())
+}
+
+func (e *exportPaginator) recordSet() *recordSet {
+	return &e.records
+}
+
+// writeStream writes the records chunkSize records at a time, flushing every
+// chunk and waiting streamDelay between the chunks
+func (e *exportPaginator) writeStream(c *gin.Context, records []any) {
+	size := e.chunkSize
+	if size <= 0 {
+		size = 1
+	}
+
+	// The stream outlives the write timeout of the server
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", e.contentType())
+	c.Status(http.StatusOK)
+
+	w := newExportWriter(e, c.Writer)
+	if err := w.writeHeader(records); err != nil {
+		return
+	}
+
+	for start := 0; start < len(records); start += size {
+		if start > 0 && e.streamDelay > 0 {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-time.After(e.streamDelay):
+			}
+		}
+
+		for _, record := range dataset.Window(records, start, size) {
+			if err := w.writeRecord(record); err != nil {
+				return
+			}
+		}
+		if err := w.flush(); err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// write writes the complete file of the records
+func (e *exportPaginator) write(out io.Writer, records []any) error {
+	w := newExportWriter(e, out)
+	if err := w.writeHeader(records); err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := w.writeRecord(record); err != nil {
+			return err
+		}
+	}
+	return w.flush()
+}
+
+func (e *exportPaginator) contentType() string {
+	if e.format == ndjsonFormat {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// exportWriter writes the records in the format of the export
+type exportWriter struct {
+	e       *exportPaginator
+	out     io.Writer
+	csv     *csv.Writer
+	columns []exportColumn
+}
+
+func newExportWriter(e *exportPaginator, out io.Writer) *exportWriter {
+	w := &exportWriter{e: e, out: out, columns: e.columns}
+	if e.format == csvFormat {
+		w.csv = csv.NewWriter(out)
+		w.csv.Comma = e.delimiter
+	}
+	return w
+}
+
+// writeHeader writes the CSV header row. Without configured columns the fields
+// of the first record are the columns, in alphabetical order.
+func (w *exportWriter) writeHeader(records []any) error {
+	if w.csv == nil {
+		return nil
+	}
+
+	if len(w.columns) == 0 && len(records) > 0 {
+		if record, ok := records[0].(map[string]any); ok {
+			keys := make([]string, 0, len(record))
+			for k := range record {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				path, _ := jsonpath.Parse(fmt.Sprintf("$[%q]", k))
+				w.columns = append(w.columns, exportColumn{name: k, path: path})
+			}
+		}
+	}
+
+	if w.e.noHeader {
+		return nil
+	}
+
+	names := make([]string, 0, len(w.columns))
+	for _, column := range w.columns {
+		names = append(names, column.name)
+	}
+	return w.csv.Write(names)
+}
+
+// writeRecord writes the record as a CSV row or a JSON line, the JSON line
+// holds only the configured columns when there are any
+func (w *exportWriter) writeRecord(record any) error {
+	if w.csv == nil {
+		if len(w.columns) > 0 {
+			selected := make(map[string]any, len(w.columns))
+			for _, column := range w.columns {
+				if value, found := column.path.Get(record); found {
+					selected[column.name] = value
+				}
+			}
+			record = selected
+		}
+
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		_, err = w.out.Write(append(line, '\n'))
+		return err
+	}
+
+	row := make([]string, 0, len(w.columns))
+	for _, column := range w.columns {
+		value, _ := column.path.Get(record)
+		row = append(row, jsonvalue.String(value))
+	}
+	return w.csv.Write(row)
+}
+
+func (w *exportWriter) flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
@@ -0,0 +1,188 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/jsonpath"
+	"mock-server/internal/jsonvalue"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultKeysetKey  = "id"
+	defaultAfterKey   = "starting_after"
+	defaultBeforeKey  = "ending_before"
+	defaultHasMoreKey = "has_more"
+)
+
+// keysetPaginator responsible for the keyset (seek) based pagination, the
+// records are ordered by the keyset field and the page starts after, or ends
+// before, the keyset value given by the request
+type keysetPaginator struct {
+	responseObj          any
+	keysetLocation       pageParameterLocation
+	responseField        jsonpath.Path
+	keysetKey            string
+	afterKey             string
+	beforeKey            string
+	hasMoreKey           string
+	paginationParameters paginationParameters
+	records              recordSet
+	metadata             metadata
+}
+
+var _ Paginator = (*keysetPaginator)(nil)
+
+// createKeysetPaginator creates a new keyset paginator for the given endpoint
+func createKeysetPaginator(endpoint config.Endpoint, source dataset.Source) (*keysetPaginator, error) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("creating keyset paginator", map[string]any{"endpoint": endpoint.Path})
+
+	k := keysetPaginator{
+		keysetLocation: pageParameterLocation(endpoint.Pagination.Location),
+		keysetKey:      defaultKeysetKey,
+		afterKey:       defaultAfterKey,
+		beforeKey:      defaultBeforeKey,
+		hasMoreKey:     defaultHasMoreKey,
+	}
+
+	if keysetKey, ok := endpoint.Pagination.Options["keysetKey"].(string); ok {
+		k.keysetKey = keysetKey
+	}
+
+	if afterKey, ok := endpoint.Pagination.Options["afterKey"].(string); ok {
+		k.afterKey = afterKey
+	}
+
+	if beforeKey, ok := endpoint.Pagination.Options["beforeKey"].(string); ok {
+		k.beforeKey = beforeKey
+	}
+
+	if hasMoreKey, ok := endpoint.Pagination.Options["hasMoreKey"].(string); ok {
+		k.hasMoreKey = hasMoreKey
+	}
+
+	responseObj, err := loadResponseObj(endpoint.ResponseObjFilePath)
+	if err != nil {
+		errInvalidResponse := fmt.Errorf("invalid response file path for endpoint: %s", endpoint.Path)
+		mockLogger.Warn(errInvalidResponse.Error(), err)
+		return nil, errors.Join(errInvalidResponse, err)
+	}
+
+	k.responseObj = responseObj
+
+	k.paginationParameters = loadPaginationParameters(endpoint)
+
+	k.metadata, err = loadMetadata(endpoint)
+	if err != nil {
+		mockLogger.Warn(err.Error(), err)
+		return nil, err
+	}
+
+	k.responseField, err = findResponseField(endpoint, responseObj)
+	if err != nil {
+		mockLogger.Warn(err.Error(), nil)
+		return nil, err
+	}
+
+	k.records = loadRecordSet(endpoint, source, responseObj, k.responseField, k.paginationParameters, k.afterKey, k.beforeKey, k.paginationParameters.pageSizeKey)
+
+	return &k, nil
+}
+
+// Paginate is the handler function for the keyset paginator
+func (k *keysetPaginator) Paginate(c *gin.Context) {
+
+	// Extract pagination params from the respective location
+	pageSize, err := requestIntParameter(c, k.keysetLocation, k.paginationParameters.pageSizeKey, k.paginationParameters.pageSize)
+	if err != nil || pageSize <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be a number"})
+		return
+	}
+
+	// The keyset value only selects the page of records ordered by it
+	if k.records.sortKey != "" && c.Query(k.records.sortKey) != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort is not supported with keyset pagination"})
+		return
+	}
+
+	allRecords, err := k.records.load(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	allRecords = k.sortByKey(allRecords)
+
+	// Walking backwards the page holds the records right before the keyset
+	// value and has_more tells whether more records exist before the page
+	if before, found := requestParameter(c, k.keysetLocation, k.beforeKey); found && before != "" {
+		end := sort.Search(len(allRecords), func(i int) bool {
+			return compareValues(k.key(allRecords[i]), before) >= 0
+		})
+		start := max(end-pageSize, 0)
+
+		records := dataset.Window(allRecords, start, end-start)
+		writeResponse(c, k.responseObj, k.responseField, k.records.project(c, records), map[string]any{k.hasMoreKey: start > 0}, k.metadata, k.pageInfo(start, pageSize, records, allRecords))
+		return
+	}
+
+	start := 0
+	if after, found := requestParameter(c, k.keysetLocation, k.afterKey); found && after != "" {
+		start = sort.Search(len(allRecords), func(i int) bool {
+			return compareValues(k.key(allRecords[i]), after) > 0
+		})
+	}
+
+	records := dataset.Window(allRecords, start, pageSize)
+	hasMore := start+len(records) < len(allRecords)
+
+	writeResponse(c, k.responseObj, k.responseField, k.records.project(c, records), map[string]any{k.hasMoreKey: hasMore}, k.metadata, k.pageInfo(start, pageSize, records, allRecords))
+}
+
+func (k *keysetPaginator) recordSet() *recordSet {
+	return &k.records
+}
+
+// pageInfo returns the pagination facts of the page, the cursors are the
+// keyset values of the last and first record of the page
+func (k *keysetPaginator) pageInfo(start, pageSize int, records, allRecords []any) pageInfo {
+	info := newPageInfo(start, pageSize, len(records), len(allRecords), 0)
+	info.hasNext = start+len(records) < len(allRecords)
+
+	if info.hasNext && len(records) > 0 {
+		info.nextCursor = k.key(records[len(records)-1])
+	}
+	if info.hasPrevious && len(records) > 0 {
+		info.prevCursor = k.key(records[0])
+	}
+
+	return info
+}
+
+// sortByKey orders the records by the keyset field
+func (k *keysetPaginator) sortByKey(records []any) []any {
+	sorted := make([]any, len(records))
+	copy(sorted, records)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareValues(k.key(sorted[i]), jsonvalue.String(k.key(sorted[j]))) < 0
+	})
+
+	return sorted
+}
+
+// key returns the keyset value of the record
+func (k *keysetPaginator) key(record any) any {
+	if m, ok := record.(map[string]any); ok {
+		return m[k.keysetKey]
+	}
+	return nil
+}
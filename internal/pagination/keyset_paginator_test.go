@@ -0,0 +1,59 @@
+package pagination
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"mock-server/internal/config"
+)
+
+func TestKeysetPaginator(t *testing.T) {
+	records := []any{
+		map[string]any{"id": "10"},
+		map[string]any{"id": "9"},
+		map[string]any{"id": "100"},
+		map[string]any{"id": "2"},
+	}
+	endpoint := testEndpoint(t, records, config.Pagination{
+		Type:    "keyset",
+		Options: map[string]any{"totalRecord": float64(4), "pageSize": float64(2)},
+	})
+	endpoint.Query = &config.Query{Filter: true}
+	p, err := CreatePaginator(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		target  string
+		status  int
+		ids     []string
+		hasMore bool
+	}{
+		{"numeric order", "/records", http.StatusOK, []string{"2", "9"}, true},
+		{"starting after", "/records?starting_after=9", http.StatusOK, []string{"10", "100"}, false},
+		{"ending before", "/records?ending_before=100", http.StatusOK, []string{"9", "10"}, true},
+		{"ending before the first", "/records?ending_before=9", http.StatusOK, []string{"2"}, false},
+		{"sort rejected", "/records?sort=-id", http.StatusBadRequest, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serve(t, p, "/records", tt.target)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, body)
+			}
+			if tt.ids == nil {
+				return
+			}
+			if got := dataIDs(t, body); !reflect.DeepEqual(got, tt.ids) {
+				t.Errorf("ids = %v, want %v", got, tt.ids)
+			}
+			if body["has_more"] != tt.hasMore {
+				t.Errorf("has_more = %v, want %v", body["has_more"], tt.hasMore)
+			}
+		})
+	}
+}
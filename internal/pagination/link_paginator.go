@@ -1,29 +1,35 @@
 package pagination
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"mock-server/internal/config"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
+	"mock-server/internal/dataset"
+	"mock-server/internal/jsonpath"
 	"mock-server/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
 type linkPaginator struct {
-	responseObj          map[string]interface{}
+	responseObj          any
 	linkKey              string
-	responseField        string
+	prevLinkKey          string
+	responseField        jsonpath.Path
 	paginationParameters paginationParameters
+	records              recordSet
+	metadata             metadata
+	sequence             *sequence
 }
 
 var _ Paginator = (*linkPaginator)(nil)
 
-func createLinkPaginator(endpoint config.Endpoint) (Paginator, error) {
+func createLinkPaginator(endpoint config.Endpoint, source dataset.Source) (Paginator, error) {
 	var tmpLogger = logger.GetLogger()
 
 	l := &linkPaginator{}
@@ -38,12 +44,22 @@ func createLinkPaginator(endpoint config.Endpoint) (Paginator, error) {
 	l.responseObj = responseObj
 
 	l.linkKey = defaultLinkKey
+	l.prevLinkKey = defaultPrevLinkKey
+
+	if prevLinkKey, ok := endpoint.Pagination.Options["prevLinkKey"].(string); ok {
+		l.prevLinkKey = prevLinkKey
+	}
 
 	l.paginationParameters = loadPaginationParameters(endpoint)
 
+	l.metadata, err = loadMetadata(endpoint)
+	if err != nil {
+		tmpLogger.Warn(err.Error(), err)
+		return nil, err
+	}
+
 	if _, ok := endpoint.Pagination.Options["linkKey"].(string); ok {
-		_, ok := responseObj[endpoint.Pagination.Options["linkKey"].(string)]
-		if !ok {
+		if !hasField(responseObj, endpoint.Pagination.Options["linkKey"].(string)) {
 			errInvalidLinkKey := fmt.Errorf("invalid link key for the endpoint: %v", endpoint.Path)
 			tmpLogger.Warn(errInvalidLinkKey.Error(), err)
 			return nil, errors.Join(errInvalidLinkKey, err)
@@ -51,97 +67,134 @@ func createLinkPaginator(endpoint config.Endpoint) (Paginator, error) {
 		l.linkKey = endpoint.Pagination.Options["linkKey"].(string)
 	}
 
-	// Validate the response field
-	if endpoint.ResponseField != "" {
-		_, ok := responseObj[endpoint.ResponseField].([]any)
-		if !ok {
-			errInvalidResponseField := fmt.Errorf("invalid response field for endpoint: %v", endpoint.Path)
-			tmpLogger.Warn(errInvalidResponseField.Error(), err)
-			return nil, errors.Join(errInvalidResponseField, err)
-		}
-		l.responseField = endpoint.ResponseField
-		return l, nil
+	// Validate the response field, or find the array field when the user not specified it
+	l.responseField, err = findResponseField(endpoint, responseObj)
+	if err != nil {
+		tmpLogger.Warn(err.Error(), err)
+		return nil, err
 	}
 
-	// If user not specified the response field, then find array field from the response object
-	if endpoint.ResponseField == "" {
-		for k, v := range responseObj {
-			if _, ok := v.([]interface{}); ok {
-				l.responseField = k
-				return l, nil
-			}
-		}
-	}
+	l.records = loadRecordSet(endpoint, source, responseObj, l.responseField, l.paginationParameters, l.paginationParameters.pageKey, l.paginationParameters.pageSizeKey)
 
-	errInvalidResponseField := fmt.Errorf("response field not present in response object for endpoint: %v", endpoint.Path)
-	tmpLogger.Warn(errInvalidResponseField.Error(), err)
-	return nil, errors.Join(errInvalidResponseField, err)
+	l.sequence = loadSequence(endpoint)
+
+	return l, nil
 }
 
 func (l *linkPaginator) Paginate(c *gin.Context) {
-	var (
-		pageSize = defaultPageSize
-	)
-
-	if l.paginationParameters.pageSentCount >= l.paginationParameters.totalPageCount {
-		c.JSON(404, gin.H{"error": "record not found"})
+	pageSize, err := requestIntParameter(c, query, l.paginationParameters.pageSizeKey, l.paginationParameters.pageSize)
+	if err != nil || pageSize <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be a number"})
 		return
 	}
 
-	if v := c.Query(l.paginationParameters.pageSizeKey); v != "" {
-		if p, err := strconv.Atoi(v); err == nil && p > 0 {
-			pageSize = p
+	var pageNumber int
+	if l.sequence != nil {
+		pageNumber, _ = l.sequence.next(pageSize)
+	} else {
+		pageNumber, err = requestIntParameter(c, query, l.paginationParameters.pageKey, 1)
+		if err != nil || pageNumber <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a number"})
+			return
 		}
 	}
 
-	arr, ok := l.responseObj[l.responseField].([]any)
-	if !ok {
-		c.JSON(500, gin.H{"error": "invalid response field"})
+	start := (pageNumber - 1) * pageSize
+	allRecords, err := l.records.load(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	object := arr[0]
-	APIResponseObject := make([]any, 0, pageSize)
-
-	if l.paginationParameters.sentRecordsCount+pageSize > l.paginationParameters.totalRecordCount {
-		pageSize = l.paginationParameters.totalRecordCount - l.paginationParameters.sentRecordsCount
+	pageLimit := l.records.pageLimit(l.paginationParameters, len(allRecords), pageSize)
+	if pageNumber > pageLimit {
+		c.JSON(404, gin.H{"error": "record not found"})
+		return
 	}
+	records := dataset.Window(allRecords, start, pageSize)
 
-	for len(APIResponseObject) < pageSize {
-		APIResponseObject = append(APIResponseObject, object)
+	// The last page has no next link and the first page no previous link
+	var nextLink, prevLink any
+	if start+len(records) < len(allRecords) && pageNumber < pageLimit {
+		nextLink = generatePageLink(c, map[string]string{l.paginationParameters.pageKey: strconv.Itoa(pageNumber + 1)})
+	}
+	if pageNumber > 1 {
+		prevLink = generatePageLink(c, map[string]string{l.paginationParameters.pageKey: strconv.Itoa(pageNumber - 1)})
 	}
 
-	l.paginationParameters.pageSentCount++
-	l.paginationParameters.sentRecordsCount += pageSize
+	info := newPageInfo(start, pageSize, len(records), len(allRecords), pageLimit)
+	info.nextCursor = nextLink
+	info.prevCursor = prevLink
 
-	l.responseObj[l.responseField] = APIResponseObject
-	l.responseObj[l.linkKey] = generatePageLink(c)
+	fields := map[string]any{l.linkKey: nextLink, l.prevLinkKey: prevLink}
 
-	jsonResponse, err := json.Marshal(l.responseObj)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create response object"})
-		return
+	// A sequential endpoint links the URL of the request, the next request gets the next page
+	if l.sequence != nil {
+		fields = map[string]any{l.linkKey: generatePageLink(c, nil)}
 	}
 
-	c.Data(http.StatusOK, "application/json", jsonResponse)
+	// A top level array sends the links in the Link header, like the GitHub API
+	if isTopLevelArray(l.responseObj, l.responseField) {
+		fields = nil
+		var links []string
+		if nextLink != nil {
+			links = append(links, fmt.Sprintf(`<%s>; rel="next"`, nextLink))
+		}
+		if prevLink != nil {
+			links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, prevLink))
+		}
+		if len(links) > 0 {
+			c.Header("Link", strings.Join(links, ", "))
+		}
+	}
 
+	writeResponse(c, l.responseObj, l.responseField, l.records.project(c, records), fields, l.metadata, info)
 }
 
-func generatePageLink(c *gin.Context) string {
-	scheme := "http"
-	if c.Request.TLS != nil || c.Request.Header.Get("X-Forwarded-Proto") == "https" {
-		scheme = "https"
-	}
+func (l *linkPaginator) recordSet() *recordSet {
+	return &l.records
+}
 
-	u := &url.URL{
-		Scheme:   scheme,
-		Host:     c.Request.Host,
-		Path:     c.Request.URL.Path,
-		RawQuery: c.Request.URL.RawQuery,
-	}
+// generatePageLink generates the link of the current request with the given query parameters replaced
+func generatePageLink(c *gin.Context, params map[string]string) string {
+	u, _ := url.Parse(BaseURL(c))
+	u.Path = c.Request.URL.Path
+	u.RawQuery = c.Request.URL.RawQuery
 
 	values, _ := url.ParseQuery(u.RawQuery)
+	for k, v := range params {
+		values.Set(k, v)
+	}
 
 	u.RawQuery = values.Encode()
 	return u.String()
 }
+
+// BaseURL returns the scheme and host the current request was sent to. Behind
+// a proxy the scheme is taken from the Forwarded or X-Forwarded-Proto header.
+func BaseURL(c *gin.Context) string {
+	return requestScheme(c) + "://" + c.Request.Host
+}
+
+// requestScheme returns the scheme of the request the client sent
+func requestScheme(c *gin.Context) string {
+	for _, element := range strings.Split(c.GetHeader("Forwarded"), ",") {
+		for _, pair := range strings.Split(element, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if found && strings.EqualFold(key, "proto") {
+				return strings.ToLower(strings.Trim(value, `"`))
+			}
+		}
+	}
+
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		// A chain of proxies lists the scheme of the client first
+		proto, _, _ = strings.Cut(proto, ",")
+		return strings.ToLower(strings.TrimSpace(proto))
+	}
+
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,75 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/template"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lookupPaginator serves a single record of the endpoint dataset by its id
+type lookupPaginator struct {
+	param   string
+	idField string
+	records recordSet
+}
+
+var _ Paginator = (*lookupPaginator)(nil)
+
+// createLookupPaginator creates a new lookup paginator for the given endpoint
+func createLookupPaginator(endpoint config.Endpoint) (*lookupPaginator, error) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("creating lookup paginator", map[string]any{"endpoint": endpoint.Path})
+
+	// The records are looked up by the idKey of the list endpoint, the config
+	// rejects a lookup field which differs from it
+	parameters := loadPaginationParameters(endpoint)
+	l := lookupPaginator{
+		param:   defaultIDKey,
+		idField: parameters.idKey,
+	}
+
+	if endpoint.Lookup.Param != "" {
+		l.param = endpoint.Lookup.Param
+	}
+
+	responseObj, err := loadResponseObj(endpoint.ResponseObjFilePath)
+	if err != nil {
+		errInvalidResponse := fmt.Errorf("invalid response file path for endpoint: %s", endpoint.Path)
+		mockLogger.Warn(errInvalidResponse.Error(), err)
+		return nil, errors.Join(errInvalidResponse, err)
+	}
+
+	responseField, err := findResponseField(endpoint, responseObj)
+	if err != nil {
+		mockLogger.Warn(err.Error(), nil)
+		return nil, err
+	}
+
+	// Build the same dataset as the list endpoint serving the response file
+	l.records = loadRecordSet(endpoint, nil, responseObj, responseField, parameters)
+
+	return &l, nil
+}
+
+// Paginate is the handler function for the lookup paginator
+func (l *lookupPaginator) Paginate(c *gin.Context) {
+	record, found := dataset.Find(l.records.source.Records(), l.idField, c.Param(l.param))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template.Render(record, c))
+}
+
+func (l *lookupPaginator) recordSet() *recordSet {
+	return &l.records
+}
@@ -0,0 +1,48 @@
+package pagination
+
+import (
+	"net/http"
+	"testing"
+
+	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+)
+
+func TestLookupPaginator(t *testing.T) {
+	records := []any{
+		map[string]any{"id": float64(999999), "name": "a"},
+	}
+	endpoint := testEndpoint(t, records, config.Pagination{
+		Options: map[string]any{"totalRecord": float64(3)},
+	})
+	endpoint.Path = "/records/:id"
+	endpoint.Lookup = &config.Lookup{}
+	p, err := CreatePaginator(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		want   string
+	}{
+		{"file record", "/records/999999", http.StatusOK, "999999"},
+		{"generated record past 1e6", "/records/1000001", http.StatusOK, "1000001"},
+		{"exponent form", "/records/1e%2B06", http.StatusNotFound, ""},
+		{"missing record", "/records/5", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serve(t, p, "/records/:id", tt.target)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, body)
+			}
+			if tt.want != "" && dataset.IDString(body["id"]) != tt.want {
+				t.Errorf("id = %v, want %s", body["id"], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+
+	"mock-server/internal/config"
+	"mock-server/internal/jsonpath"
+	"mock-server/internal/jsonvalue"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errInvalidMetadata = errors.New("invalid pagination metadata")
+)
+
+// pageInfo holds the pagination facts of the served page
+type pageInfo struct {
+	currentPage  int
+	pageSize     int
+	totalPages   int
+	totalRecords int
+	offset       int
+	hasNext      bool
+	hasPrevious  bool
+	nextCursor   any
+	prevCursor   any
+}
+
+// facts are the pagination facts the metadata can report
+var facts = map[string]func(info pageInfo) any{
+	"currentPage":  func(info pageInfo) any { return info.currentPage },
+	"pageSize":     func(info pageInfo) any { return info.pageSize },
+	"totalPages":   func(info pageInfo) any { return info.totalPages },
+	"totalRecords": func(info pageInfo) any { return info.totalRecords },
+	"offset":       func(info pageInfo) any { return info.offset },
+	"hasNext":      func(info pageInfo) any { return info.hasNext },
+	"hasPrevious":  func(info pageInfo) any { return info.hasPrevious },
+	"nextCursor":   func(info pageInfo) any { return info.nextCursor },
+	"prevCursor":   func(info pageInfo) any { return info.prevCursor },
+}
+
+// metadataField places a pagination fact at a path of the response body
+type metadataField struct {
+	path jsonpath.Path
+	fact string
+}
+
+// metadata reports the pagination facts in the response body and headers
+type metadata struct {
+	body    []metadataField
+	headers map[string]string
+}
+
+// loadMetadata loads the metadata of the endpoint
+func loadMetadata(endpoint config.Endpoint) (metadata, error) {
+	m := metadata{}
+
+	if endpoint.Metadata == nil {
+		return m, nil
+	}
+
+	for location, fact := range endpoint.Metadata.Body {
+		if _, ok := facts[fact]; !ok {
+			return m, fmt.Errorf("%w: unknown fact %s for endpoint: %s", errInvalidMetadata, fact, endpoint.Path)
+		}
+		path, err := jsonpath.Parse(location)
+		if err != nil {
+			return m, errors.Join(errInvalidMetadata, err)
+		}
+		m.body = append(m.body, metadataField{path: path, fact: fact})
+	}
+
+	for _, fact := range endpoint.Metadata.Headers {
+		if _, ok := facts[fact]; !ok {
+			return m, fmt.Errorf("%w: unknown fact %s for endpoint: %s", errInvalidMetadata, fact, endpoint.Path)
+		}
+	}
+	m.headers = endpoint.Metadata.Headers
+
+	return m, nil
+}
+
+// apply writes the pagination facts into the response body and headers
+func (m metadata) apply(c *gin.Context, response any, info pageInfo) {
+	for _, field := range m.body {
+		// The paths are validated on load, a path through an array index or into a
+		// top level array can still miss
+		_ = field.path.Set(response, facts[field.fact](info))
+	}
+
+	m.applyHeaders(c, info)
+}
+
+// applyHeaders writes the pagination facts into the response headers
+func (m metadata) applyHeaders(c *gin.Context, info pageInfo) {
+	for header, fact := range m.headers {
+		if value := facts[fact](info); value != nil {
+			c.Header(header, jsonvalue.String(value))
+		}
+	}
+}
+
+// newPageInfo returns the pagination facts of the page of records in [start, start+size)
+func newPageInfo(start, size, count, totalRecords, maxPages int) pageInfo {
+	totalPages := (totalRecords + size - 1) / size
+	if maxPages > 0 && totalPages > maxPages {
+		totalPages = maxPages
+	}
+
+	return pageInfo{
+		currentPage:  start/size + 1,
+		pageSize:     size,
+		totalPages:   totalPages,
+		totalRecords: totalRecords,
+		offset:       start,
+		hasNext:      start+count < totalRecords && start/size+1 < totalPages,
+		hasPrevious:  start > 0,
+	}
+}
@@ -0,0 +1,78 @@
+package pagination
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mock-server/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetadata(t *testing.T) {
+	records := []any{
+		map[string]any{"id": float64(1000000)},
+		map[string]any{"id": float64(1000001)},
+		map[string]any{"id": float64(1000002)},
+	}
+
+	tests := []struct {
+		name       string
+		pagination config.Pagination
+		target     string
+		headers    map[string]string
+		body       map[string]any
+	}{
+		{
+			name:       "page facts",
+			pagination: config.Pagination{Type: "page", Options: map[string]any{"totalRecord": float64(3), "pageSize": float64(2)}},
+			target:     "/records?page=2",
+			headers:    map[string]string{"X-Total-Count": "3", "X-Page": "2"},
+			body:       map[string]any{"totalPages": float64(2), "hasNext": false},
+		},
+		{
+			name:       "keyset cursor of a large id",
+			pagination: config.Pagination{Type: "keyset", Options: map[string]any{"totalRecord": float64(3), "pageSize": float64(1)}},
+			target:     "/records?starting_after=1000000",
+			headers:    map[string]string{"X-Next-Cursor": "1000001", "X-Prev-Cursor": "1000001"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := testEndpoint(t, records, tt.pagination)
+			endpoint.Metadata = &config.Metadata{
+				Body: map[string]string{"$.meta.totalPages": "totalPages", "$.meta.hasNext": "hasNext"},
+				Headers: map[string]string{
+					"X-Total-Count": "totalRecords",
+					"X-Page":        "currentPage",
+					"X-Next-Cursor": "nextCursor",
+					"X-Prev-Cursor": "prevCursor",
+				},
+			}
+			p, err := CreatePaginator(endpoint)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			engine := gin.New()
+			engine.GET("/records", p.Paginate)
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			for header, want := range tt.headers {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+
+			_, body := serve(t, p, "/records", tt.target)
+			for key, want := range tt.body {
+				if got := body["meta"].(map[string]any)[key]; got != want {
+					t.Errorf("meta.%s = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
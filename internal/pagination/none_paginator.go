@@ -0,0 +1,184 @@
+package pagination
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/jsonpath"
+	"mock-server/internal/jsonvalue"
+	"mock-server/internal/template"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// nonePaginator serves the response object as it is, without pagination
+type nonePaginator struct {
+	responseObj     any
+	rawBody         []byte
+	contentType     string
+	statusCode      int
+	responseHeaders map[string]any
+	responseField   jsonpath.Path
+	records         *recordSet
+	recordCount     int
+	metadata        metadata
+}
+
+var _ Paginator = (*nonePaginator)(nil)
+
+// createNonePaginator creates a paginator which always returns the complete response object
+func createNonePaginator(endpoint config.Endpoint, source dataset.Source) (*nonePaginator, error) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("creating none paginator", map[string]any{"endpoint": endpoint.Path})
+
+	n := nonePaginator{
+		statusCode:      http.StatusOK,
+		responseHeaders: endpoint.ResponseHeaders,
+	}
+
+	if endpoint.StatusCode != 0 {
+		n.statusCode = endpoint.StatusCode
+	}
+
+	// Text, XML and binary files are served as they are
+	if !isJSONFile(endpoint.ResponseObjFilePath) {
+		rawBody, err := loadResponseFile(endpoint.ResponseObjFilePath)
+		if err != nil {
+			errInvalidResponse := fmt.Errorf("invalid response file path for endpoint: %s", endpoint.Path)
+			mockLogger.Warn(errInvalidResponse.Error(), err)
+			return nil, errors.Join(errInvalidResponse, err)
+		}
+
+		n.rawBody = rawBody
+		n.contentType = mime.TypeByExtension(filepath.Ext(endpoint.ResponseObjFilePath))
+		if n.contentType == "" {
+			n.contentType = http.DetectContentType(rawBody)
+		}
+
+		// Only the headers can report the pagination facts of a raw body
+		n.metadata, err = loadMetadata(endpoint)
+		if err == nil && len(n.metadata.body) > 0 {
+			err = fmt.Errorf("%w: body metadata needs a JSON response file for endpoint: %s", errInvalidMetadata, endpoint.Path)
+		}
+		if err != nil {
+			mockLogger.Warn(err.Error(), err)
+			return nil, err
+		}
+
+		return &n, nil
+	}
+
+	responseObj, err := loadResponseObj(endpoint.ResponseObjFilePath)
+	if err != nil {
+		errInvalidResponse := fmt.Errorf("invalid response file path for endpoint: %s", endpoint.Path)
+		mockLogger.Warn(errInvalidResponse.Error(), err)
+		return nil, errors.Join(errInvalidResponse, err)
+	}
+
+	n.responseObj = responseObj
+
+	n.metadata, err = loadMetadata(endpoint)
+	if err != nil {
+		mockLogger.Warn(err.Error(), err)
+		return nil, err
+	}
+
+	// A source, a timeline or a query replaces the array of the response object with all of its records
+	if source != nil || endpoint.Timeline != nil || endpoint.Query != nil {
+		responseField, err := findResponseField(endpoint, responseObj)
+		if err != nil {
+			mockLogger.Warn(err.Error(), nil)
+			return nil, err
+		}
+		n.responseField = responseField
+		records := loadRecordSet(endpoint, source, responseObj, responseField, loadPaginationParameters(endpoint))
+		n.records = &records
+	} else if responseField, err := findResponseField(endpoint, responseObj); err == nil {
+		n.recordCount = len(arrayAt(responseObj, responseField))
+	}
+
+	return &n, nil
+}
+
+// Paginate is the handler function for the none paginator
+func (n *nonePaginator) Paginate(c *gin.Context) {
+	if n.rawBody != nil {
+		n.writeRawBody(c)
+		return
+	}
+
+	response := template.Render(n.responseObj, c)
+	count := n.recordCount
+	if n.records != nil {
+		records, err := n.records.load(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if n.responseField.IsRoot() {
+			response = template.Render(n.records.project(c, records), c)
+		} else {
+			_ = n.responseField.Set(response, template.Render(n.records.project(c, records), c))
+		}
+		count = len(records)
+	}
+
+	// All the records are served as a single page
+	n.metadata.apply(c, response, newPageInfo(0, max(count, 1), count, count, 1))
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create response object"})
+		return
+	}
+
+	for k, v := range n.responseHeaders {
+		c.Header(k, jsonvalue.String(v))
+	}
+
+	c.Data(n.statusCode, "application/json", jsonResponse)
+}
+
+func (n *nonePaginator) recordSet() *recordSet {
+	return n.records
+}
+
+// writeRawBody writes the response file as it is, the placeholders of text
+// bodies are replaced with the values of the request
+func (n *nonePaginator) writeRawBody(c *gin.Context) {
+	for k, v := range n.responseHeaders {
+		c.Header(k, jsonvalue.String(v))
+	}
+	n.metadata.applyHeaders(c, newPageInfo(0, 1, 0, 0, 1))
+
+	body := n.rawBody
+	if isXMLContent(n.contentType) {
+		body = []byte(template.RenderXML(string(body), c))
+	} else if isTextContent(n.contentType) {
+		body = []byte(template.RenderString(string(body), c))
+	}
+
+	c.Data(n.statusCode, n.contentType, body)
+}
+
+// isTextContent tells whether the content type is text, like text/plain or application/xml
+func isTextContent(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "xml") || strings.HasSuffix(mediaType, "json")
+}
+
+// isXMLContent tells whether the content type is XML, like application/xml or image/svg+xml
+func isXMLContent(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasSuffix(mediaType, "xml")
+}
@@ -1,29 +1,31 @@
 package pagination
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"reflect"
-	"strconv"
 
 	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/jsonpath"
 	"mock-server/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
 type offsetPaginator struct {
-	responseObj          map[string]interface{}
+	responseObj          any
 	offsetLocation       pageParameterLocation
-	responseField        string
+	responseField        jsonpath.Path
 	paginationParameters paginationParameters
+	records              recordSet
+	metadata             metadata
+	sequence             *sequence
 }
 
 var _ Paginator = (*offsetPaginator)(nil)
 
-func createOffsetPaginator(endpoint config.Endpoint) (Paginator, error) {
+func createOffsetPaginator(endpoint config.Endpoint, source dataset.Source) (Paginator, error) {
 	var tmpLogger = logger.GetLogger()
 
 	tmpLogger.InfoW("creating offset paginator", map[string]any{"endpoint": endpoint.Path})
@@ -43,110 +45,67 @@ func createOffsetPaginator(endpoint config.Endpoint) (Paginator, error) {
 
 	o.paginationParameters = loadPaginationParameters(endpoint)
 
-	// Validate the response field
-	if endpoint.ResponseField != "" {
-		_, ok := responseObj[endpoint.ResponseField].([]any)
-		if !ok {
-			errInvalidResponseField := fmt.Errorf("invalid response field for endpoint: %v", endpoint.Path)
-			tmpLogger.Warn(errInvalidResponseField.Error(), err)
-			return nil, errors.Join(errInvalidResponseField, err)
-		}
-		o.responseField = endpoint.ResponseField
-		return &o, nil
+	o.metadata, err = loadMetadata(endpoint)
+	if err != nil {
+		tmpLogger.Warn(err.Error(), err)
+		return nil, err
 	}
 
-	// If user not specified the response field, then find array field from the response object
-	if endpoint.ResponseField == "" {
-		for k, v := range responseObj {
-			if _, ok := v.([]interface{}); ok {
-				o.responseField = k
-				return &o, nil
-			}
-		}
+	// Validate the response field, or find the array field when the user not specified it
+	o.responseField, err = findResponseField(endpoint, responseObj)
+	if err != nil {
+		tmpLogger.Warn(err.Error(), err)
+		return nil, err
 	}
 
-	errInvalidResponseField := fmt.Errorf("response field not present in response object for endpoint: %v", endpoint.Path)
-	tmpLogger.Warn(errInvalidResponseField.Error(), err)
-	return nil, errors.Join(errInvalidResponseField, err)
+	o.records = loadRecordSet(endpoint, source, responseObj, o.responseField, o.paginationParameters, o.paginationParameters.offsetKey, o.paginationParameters.pageSizeKey)
+
+	o.sequence = loadSequence(endpoint)
+
+	return &o, nil
 }
 
 func (o *offsetPaginator) Paginate(c *gin.Context) {
-	var (
-		pageSize  = defaultPageSize
-		tmpLogger = logger.GetLogger()
-	)
+	var tmpLogger = logger.GetLogger()
 
 	// Extract pagination params from the respective location
-	switch o.offsetLocation {
-	case body:
-		var requestBody map[string]interface{}
-		err := c.ShouldBindJSON(&requestBody)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse request body"})
-			return
-		}
-		value, found := requestBody[o.paginationParameters.pageSizeKey]
-		if found {
-			switch v := value.(type) {
-			case float64:
-				pageSize = int(v)
-			case int:
-				// Already an integer
-				pageSize = v
-			case int32, int64:
-				// Handle other integer types
-				pageSize = int(reflect.ValueOf(v).Int())
-			case uint, uint32, uint64:
-				// Handle unsigned integers
-				pageSize = int(reflect.ValueOf(v).Uint())
-			default:
-				c.JSON(http.StatusBadRequest, gin.H{"error": "size must be a number"})
-				return
-			}
-		}
+	pageSize, err := requestIntParameter(c, o.offsetLocation, o.paginationParameters.pageSizeKey, o.paginationParameters.pageSize)
+	if err != nil || pageSize <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be a number"})
+		return
+	}
 
-	case header:
-		if v := c.GetHeader(o.paginationParameters.pageSizeKey); v != "" {
-			if p, err := strconv.Atoi(v); err == nil && p > 0 {
-				pageSize = p
-			}
-		}
-	case query:
-		size, err := strconv.Atoi(c.DefaultQuery(o.paginationParameters.pageSizeKey, strconv.Itoa(defaultPageSize)))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sizeValue"})
+	var offsetValue int
+	if o.sequence != nil {
+		_, offsetValue = o.sequence.next(pageSize)
+	} else {
+		offsetValue, err = requestIntParameter(c, o.offsetLocation, o.paginationParameters.offsetKey, 0)
+		if err != nil || offsetValue < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a number"})
 			return
 		}
-		pageSize = size
 	}
 
-	tmpLogger.InfoW("page value size", map[string]any{"size": pageSize})
+	tmpLogger.InfoW("page value size", map[string]any{"size": pageSize, "offset": offsetValue})
 
-	// 3. Find the response object
-	arr, ok := o.responseObj[o.responseField].([]any)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid response field"})
+	allRecords, err := o.records.load(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	records := dataset.Window(allRecords, offsetValue, pageSize)
 
-	object := arr[0]
-	APIResponseObject := make([]any, 0, pageSize)
-
-	if o.paginationParameters.sentRecordsCount+pageSize > o.paginationParameters.totalRecordCount {
-		pageSize = o.paginationParameters.totalRecordCount - o.paginationParameters.sentRecordsCount
+	info := newPageInfo(offsetValue, pageSize, len(records), len(allRecords), 0)
+	if info.hasNext {
+		info.nextCursor = offsetValue + len(records)
 	}
-
-	for len(APIResponseObject) < int(pageSize) {
-		APIResponseObject = append(APIResponseObject, object)
+	if info.hasPrevious {
+		info.prevCursor = max(offsetValue-pageSize, 0)
 	}
 
-	o.responseObj[o.responseField] = APIResponseObject
-
-	jsonResponse, err := json.Marshal(o.responseObj)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create response object"})
-		return
-	}
+	writeResponse(c, o.responseObj, o.responseField, o.records.project(c, records), nil, o.metadata, info)
+}
 
-	c.Data(http.StatusOK, "application/json", jsonResponse)
+func (o *offsetPaginator) recordSet() *recordSet {
+	return &o.records
 }
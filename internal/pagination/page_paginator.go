@@ -1,14 +1,13 @@
 package pagination
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"reflect"
-	"strconv"
 
 	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/jsonpath"
 	"mock-server/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -16,14 +15,17 @@ import (
 
 // pagePaginator responsible for the page based pagination
 type pagePaginator struct {
-	responseObj          map[string]interface{}
+	responseObj          any
 	pageParamsLocation   pageParameterLocation
-	responseField        string
+	responseField        jsonpath.Path
 	paginationParameters paginationParameters
+	records              recordSet
+	metadata             metadata
+	sequence             *sequence
 }
 
 // createPagePaginator creates a new page paginator for the given endpoint
-func createPagePaginator(endpoint config.Endpoint) (*pagePaginator, error) {
+func createPagePaginator(endpoint config.Endpoint, source dataset.Source) (*pagePaginator, error) {
 	var mockLogger = logger.GetLogger()
 
 	mockLogger.InfoW("creating page paginator", map[string]any{"endpoint": endpoint.Path})
@@ -43,114 +45,71 @@ func createPagePaginator(endpoint config.Endpoint) (*pagePaginator, error) {
 
 	p.paginationParameters = loadPaginationParameters(endpoint)
 
-	// Validate the response field
-	if endpoint.ResponseField != "" {
-		_, ok := responseObj[endpoint.ResponseField].([]any)
-		if !ok {
-			errInvalidResponseField := fmt.Errorf("invalid response field for endpoint: %v", endpoint.Path)
-			mockLogger.Warn(errInvalidResponseField.Error(), err)
-			return nil, errors.Join(errInvalidResponseField, err)
-		}
-		p.responseField = endpoint.ResponseField
-		return &p, nil
+	p.metadata, err = loadMetadata(endpoint)
+	if err != nil {
+		mockLogger.Warn(err.Error(), err)
+		return nil, err
 	}
 
-	if endpoint.ResponseField == "" {
-		for k, v := range responseObj {
-			if _, ok := v.([]interface{}); ok {
-				p.responseField = k
-				return &p, nil
-			}
-		}
+	// Validate the response field, or find the array field when the user not specified it
+	p.responseField, err = findResponseField(endpoint, responseObj)
+	if err != nil {
+		mockLogger.Warn(err.Error(), err)
+		return nil, err
 	}
 
-	errInvalidResponseField := fmt.Errorf("response field not present in response object for endpoint: %v", endpoint.Path)
-	mockLogger.Warn(errInvalidResponseField.Error(), err)
-	return nil, errors.Join(errInvalidResponseField, err)
+	p.records = loadRecordSet(endpoint, source, responseObj, p.responseField, p.paginationParameters, p.paginationParameters.pageKey, p.paginationParameters.pageSizeKey)
+
+	p.sequence = loadSequence(endpoint)
+
+	return &p, nil
 }
 
 // Paginate is the handler function for the page paginator
 func (p *pagePaginator) Paginate(c *gin.Context) {
 
-	var pageSize = defaultPageSize
-
-	if p.paginationParameters.pageSentCount >= p.paginationParameters.totalPageCount {
-		c.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
-	}
-
 	// Extract pagination params from the respective location
-	switch p.pageParamsLocation {
-	case body:
-		var requestBody map[string]interface{}
-		err := c.ShouldBindJSON(&requestBody)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse request body"})
-			return
-		}
-		value, found := requestBody[p.paginationParameters.pageSizeKey]
-		if found {
-			switch v := value.(type) {
-			case float64:
-				pageSize = int(v)
-			case int:
-				// Already an integer
-				pageSize = v
-			case int32, int64:
-				// Handle other integer types
-				pageSize = int(reflect.ValueOf(v).Int())
-			case uint, uint32, uint64:
-				// Handle unsigned integers
-				pageSize = int(reflect.ValueOf(v).Uint())
-			default:
-				c.JSON(http.StatusBadRequest, gin.H{"error": "size must be a number"})
-				return
-			}
-		}
+	pageSize, err := requestIntParameter(c, p.pageParamsLocation, p.paginationParameters.pageSizeKey, p.paginationParameters.pageSize)
+	if err != nil || pageSize <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be a number"})
+		return
+	}
 
-	case header:
-		if v := c.GetHeader(p.paginationParameters.pageSizeKey); v != "" {
-			if p, err := strconv.Atoi(v); err == nil && p > 0 {
-				pageSize = p
-			}
-		}
-	case query:
-		size, err := strconv.Atoi(c.DefaultQuery(p.paginationParameters.pageSizeKey, strconv.Itoa(defaultPageSize)))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sizeValue"})
+	var pageNumber int
+	if p.sequence != nil {
+		pageNumber, _ = p.sequence.next(pageSize)
+	} else {
+		pageNumber, err = requestIntParameter(c, p.pageParamsLocation, p.paginationParameters.pageKey, 1)
+		if err != nil || pageNumber <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a number"})
 			return
 		}
-		pageSize = size
 	}
 
-	// 3. Find the response object
-	arr, ok := p.responseObj[p.responseField].([]any)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid response field"})
+	allRecords, err := p.records.load(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	object := arr[0]
-	APIResponseObject := make([]any, 0, pageSize)
-
-	if p.paginationParameters.sentRecordsCount+pageSize > p.paginationParameters.totalRecordCount {
-		pageSize = p.paginationParameters.totalRecordCount - p.paginationParameters.sentRecordsCount
+	pageLimit := p.records.pageLimit(p.paginationParameters, len(allRecords), pageSize)
+	if pageNumber > pageLimit {
+		c.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
+		return
 	}
+	records := dataset.Window(allRecords, (pageNumber-1)*pageSize, pageSize)
 
-	for len(APIResponseObject) < pageSize {
-		APIResponseObject = append(APIResponseObject, object)
+	info := newPageInfo((pageNumber-1)*pageSize, pageSize, len(records), len(allRecords), pageLimit)
+	if info.hasNext {
+		info.nextCursor = pageNumber + 1
 	}
-
-	p.paginationParameters.pageSentCount++
-	p.paginationParameters.sentRecordsCount += pageSize
-
-	p.responseObj[p.responseField] = APIResponseObject
-
-	jsonResponse, err := json.Marshal(p.responseObj)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create response object"})
-		return
+	if info.hasPrevious {
+		info.prevCursor = pageNumber - 1
 	}
 
-	c.Data(http.StatusOK, "application/json", jsonResponse)
+	writeResponse(c, p.responseObj, p.responseField, p.records.project(c, records), nil, p.metadata, info)
+}
 
+func (p *pagePaginator) recordSet() *recordSet {
+	return &p.records
 }
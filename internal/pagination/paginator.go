@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"mock-server/internal/config"
+	"mock-server/internal/dataset"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,6 +19,7 @@ const (
 	none   paginationType = "none"
 	link   paginationType = "link"
 	offset paginationType = "offset"
+	keyset paginationType = "keyset"
 
 	body   pageParameterLocation = "body"
 	query  pageParameterLocation = "query"
@@ -35,38 +37,82 @@ type paginationParameters struct {
 	totalRecordCount   int
 	pageKey            string
 	pageSizeKey        string
-	pageSentCount      int
+	offsetKey          string
+	tokenKey           string
+	idKey              string
 	pageSize           int
-	sentRecordsCount   int
 }
 
 func CreatePaginator(endpoint config.Endpoint) (Paginator, error) {
+	return CreateSourcePaginator(endpoint, nil)
+}
+
+// CreateSourcePaginator creates the paginator of the endpoint paginating over the
+// records of the given source instead of the records of the response file
+func CreateSourcePaginator(endpoint config.Endpoint, source dataset.Source) (Paginator, error) {
+
+	if endpoint.Lookup != nil {
+		p, err := createLookupPaginator(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create lookup paginator for endpoint: %s", endpoint.Path)
+		}
+		return p, nil
+	}
+
+	if endpoint.Export != nil {
+		p, err := createExportPaginator(endpoint, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create export paginator for endpoint: %s", endpoint.Path)
+		}
+		return p, nil
+	}
+
+	// SSE endpoints stream their dataset instead of paginating it
+	if endpoint.Type == sseEndpoint {
+		p, err := createSSEPaginator(endpoint, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sse paginator for endpoint: %s", endpoint.Path)
+		}
+		return p, nil
+	}
 
 	switch paginationType(endpoint.Pagination.Type) {
 	case page:
-		p, err := createPagePaginator(endpoint)
+		p, err := createPagePaginator(endpoint, source)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create page paginator for endpoint: %s", endpoint.Path)
 		}
 		return p, nil
 	case offset:
-		p, err := createOffsetPaginator(endpoint)
+		p, err := createOffsetPaginator(endpoint, source)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create offset paginator for endpoint: %s", endpoint.Path)
 		}
 		return p, nil
 	case link:
-		p, err := createLinkPaginator(endpoint)
+		p, err := createLinkPaginator(endpoint, source)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create link paginator for endpoint: %s", endpoint.Path)
 		}
 		return p, nil
 	case token:
-		p, err := createTokenPaginator(endpoint)
+		p, err := createTokenPaginator(endpoint, source)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create token paginator for endpoint : %s", endpoint.Path)
 		}
 		return p, nil
+	case keyset:
+		p, err := createKeysetPaginator(endpoint, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create keyset paginator for endpoint: %s", endpoint.Path)
+		}
+		return p, nil
+	case none, "":
+		p, err := createNonePaginator(endpoint, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create none paginator for endpoint: %s", endpoint.Path)
+		}
+		return p, nil
 	default:
 		return nil, fmt.Errorf("unsupported pagination type: %s", endpoint.Pagination.Type)
 	}
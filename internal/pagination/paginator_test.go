@@ -0,0 +1,216 @@
+package pagination
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testRecords returns n records with the ids 1 to n
+func testRecords(n int) []any {
+	records := make([]any, 0, n)
+	for i := 1; i <= n; i++ {
+		records = append(records, map[string]any{"id": float64(i), "name": "record"})
+	}
+	return records
+}
+
+// testEndpoint returns an endpoint serving the records in the data field of its response file
+func testEndpoint(t *testing.T, records []any, pagination config.Pagination) config.Endpoint {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"data": records})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "response.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return config.Endpoint{
+		Path:                "/records",
+		Method:              http.MethodGet,
+		Pagination:          pagination,
+		ResponseObjFilePath: path,
+	}
+}
+
+// serve sends the request to the paginator and returns the status and the decoded body
+func serve(t *testing.T, p Paginator, route, target string) (int, map[string]any) {
+	t.Helper()
+
+	engine := gin.New()
+	engine.GET(route, p.Paginate)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+	}
+	return w.Code, body
+}
+
+// dataIDs returns the ids of the records in the data field of the body
+func dataIDs(t *testing.T, body map[string]any) []string {
+	t.Helper()
+
+	records, ok := body["data"].([]any)
+	if !ok {
+		t.Fatalf("no records in %v", body)
+	}
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, dataset.IDString(r.(map[string]any)["id"]))
+	}
+	return ids
+}
+
+func TestPagePaginator(t *testing.T) {
+	endpoint := testEndpoint(t, testRecords(2), config.Pagination{
+		Type:    "page",
+		Options: map[string]any{"totalRecord": float64(5), "pageSize": float64(2), "totalPage": float64(3)},
+	})
+	p, err := CreatePaginator(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		ids    []string
+	}{
+		{"first page by default", "/records", http.StatusOK, []string{"1", "2"}},
+		{"second page with generated ids", "/records?page=2", http.StatusOK, []string{"3", "4"}},
+		{"last partial page", "/records?page=3", http.StatusOK, []string{"5"}},
+		{"page size", "/records?page=1&pageSize=4", http.StatusOK, []string{"1", "2", "3", "4"}},
+		{"past the last page", "/records?page=4", http.StatusNotFound, nil},
+		{"invalid page", "/records?page=x", http.StatusBadRequest, nil},
+		{"zero page", "/records?page=0", http.StatusBadRequest, nil},
+		{"invalid page size", "/records?pageSize=0", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serve(t, p, "/records", tt.target)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, body)
+			}
+			if tt.ids != nil {
+				if got := dataIDs(t, body); !reflect.DeepEqual(got, tt.ids) {
+					t.Errorf("ids = %v, want %v", got, tt.ids)
+				}
+			}
+		})
+	}
+}
+
+func TestOffsetPaginator(t *testing.T) {
+	endpoint := testEndpoint(t, testRecords(5), config.Pagination{
+		Type:    "offset",
+		Options: map[string]any{"totalRecord": float64(5), "pageSize": float64(2)},
+	})
+	p, err := CreatePaginator(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		ids    []string
+	}{
+		{"first page by default", "/records", http.StatusOK, []string{"1", "2"}},
+		{"offset", "/records?offset=3", http.StatusOK, []string{"4", "5"}},
+		{"past the end", "/records?offset=5", http.StatusOK, []string{}},
+		{"invalid offset", "/records?offset=x", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serve(t, p, "/records", tt.target)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, body)
+			}
+			if tt.ids != nil {
+				if got := dataIDs(t, body); !reflect.DeepEqual(got, tt.ids) {
+					t.Errorf("ids = %v, want %v", got, tt.ids)
+				}
+			}
+		})
+	}
+}
+
+func TestSequentialPaginators(t *testing.T) {
+	for _, typ := range []string{"page", "offset", "token", "link"} {
+		t.Run(typ, func(t *testing.T) {
+			endpoint := testEndpoint(t, testRecords(2), config.Pagination{
+				Type:    typ,
+				Options: map[string]any{"totalRecord": float64(5), "pageSize": float64(2), "totalPage": float64(2), "sequential": true},
+			})
+			p, err := CreatePaginator(endpoint)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The parameters of the request are ignored, every request gets the next page
+			for _, ids := range [][]string{{"1", "2"}, {"3", "4"}} {
+				status, body := serve(t, p, "/records", "/records?page=9&offset=3")
+				if status != http.StatusOK {
+					t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, body)
+				}
+				if got := dataIDs(t, body); !reflect.DeepEqual(got, ids) {
+					t.Errorf("ids = %v, want %v", got, ids)
+				}
+				if _, found := body["token"]; found {
+					t.Errorf("token written: %v", body["token"])
+				}
+				if link, found := body["link"]; typ == "link" && (!found || link != "http://example.com/records?offset=3&page=9") {
+					t.Errorf("link = %v, want the URL of the request", link)
+				}
+			}
+
+			if status, body := serve(t, p, "/records", "/records"); typ != "offset" && status != http.StatusNotFound {
+				t.Errorf("status after the last page = %d, want %d: %v", status, http.StatusNotFound, body)
+			}
+		})
+	}
+}
+
+func TestNonePaginatorResponseHeaders(t *testing.T) {
+	endpoint := testEndpoint(t, testRecords(1), config.Pagination{Type: "none"})
+	endpoint.ResponseHeaders = map[string]any{"X-Total": float64(2000000), "X-Cached": true, "X-Vendor": "acme"}
+	p, err := CreatePaginator(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := gin.New()
+	engine.GET("/records", p.Paginate)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/records", nil))
+
+	for header, want := range map[string]string{"X-Total": "2000000", "X-Cached": "true", "X-Vendor": "acme"} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package pagination
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mock-server/internal/config"
+	"mock-server/internal/jsonpath"
+	"mock-server/internal/jsonvalue"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSortKey   = "sort"
+	defaultFieldsKey = "fields"
+)
+
+// filterOperators compare the record value with the filter value, the
+// operator is given in brackets after the field: severity[gte]=3
+var filterOperators = map[string]func(actual any, value string) bool{
+	"eq": func(actual any, value string) bool {
+		return compareValues(actual, value) == 0
+	},
+	"ne": func(actual any, value string) bool {
+		return compareValues(actual, value) != 0
+	},
+	"gt": func(actual any, value string) bool {
+		return compareValues(actual, value) > 0
+	},
+	"gte": func(actual any, value string) bool {
+		return compareValues(actual, value) >= 0
+	},
+	"lt": func(actual any, value string) bool {
+		return compareValues(actual, value) < 0
+	},
+	"lte": func(actual any, value string) bool {
+		return compareValues(actual, value) <= 0
+	},
+	"in": func(actual any, value string) bool {
+		for _, v := range strings.Split(value, ",") {
+			if compareValues(actual, v) == 0 {
+				return true
+			}
+		}
+		return false
+	},
+	"contains": func(actual any, value string) bool {
+		return strings.Contains(jsonvalue.String(actual), value)
+	},
+}
+
+// queryStages returns the stages filtering and sorting the records by the query
+// parameters of the request. The keys in reserved are never used as filters.
+func queryStages(q *config.Query, reserved []string) []stage {
+	sortKey := defaultSortKey
+	if q.SortKey != "" {
+		sortKey = q.SortKey
+	}
+
+	fieldsKey := defaultFieldsKey
+	if q.FieldsKey != "" {
+		fieldsKey = q.FieldsKey
+	}
+
+	ignored := map[string]bool{sortKey: true, fieldsKey: true}
+	for _, key := range reserved {
+		ignored[key] = true
+	}
+
+	var stages []stage
+	if q.Filter {
+		stages = append(stages, filterStage(q.FilterFields, ignored))
+	}
+	stages = append(stages, sortStage(sortKey))
+
+	return stages
+}
+
+// filterStage keeps the records matching all the filter parameters of the request
+func filterStage(allowed []string, ignored map[string]bool) stage {
+	allowedFields := make(map[string]bool, len(allowed))
+	for _, field := range allowed {
+		allowedFields[field] = true
+	}
+
+	return func(c *gin.Context, records []any) ([]any, error) {
+		type filter struct {
+			path    jsonpath.Path
+			compare func(actual any, value string) bool
+			value   string
+		}
+
+		var filters []filter
+		for param, values := range c.Request.URL.Query() {
+			if ignored[param] || len(values) == 0 {
+				continue
+			}
+
+			field, operator := param, "eq"
+			if i := strings.Index(param, "["); i > 0 && strings.HasSuffix(param, "]") {
+				field, operator = param[:i], param[i+1:len(param)-1]
+			}
+
+			if len(allowedFields) > 0 && !allowedFields[field] {
+				continue
+			}
+
+			path, err := jsonpath.Parse(field)
+			if err != nil && len(allowedFields) > 0 {
+				return nil, fmt.Errorf("%w: %s", errInvalidFilter, param)
+			}
+
+			// Without filter fields only the fields of the records filter, so that
+			// the other parameters of the request, e.g. an api key, are ignored
+			if len(allowedFields) == 0 && (err != nil || !hasPath(records, path)) {
+				continue
+			}
+
+			compare, ok := filterOperators[operator]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", errInvalidFilter, param)
+			}
+
+			filters = append(filters, filter{path: path, compare: compare, value: values[0]})
+		}
+
+		if len(filters) == 0 {
+			return records, nil
+		}
+
+		filtered := make([]any, 0, len(records))
+		for _, record := range records {
+			matches := true
+			for _, f := range filters {
+				actual, found := f.path.Get(record)
+				if !found || !f.compare(actual, f.value) {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				filtered = append(filtered, record)
+			}
+		}
+
+		return filtered, nil
+	}
+}
+
+// hasPath tells whether any of the records has a value at the path
+func hasPath(records []any, path jsonpath.Path) bool {
+	for _, record := range records {
+		if _, found := path.Get(record); found {
+			return true
+		}
+	}
+	return false
+}
+
+// sortStage sorts the records by the fields of the sort parameter, e.g.
+// sort=severity,-created_at sorts by severity ascending then created_at descending
+func sortStage(sortKey string) stage {
+	return func(c *gin.Context, records []any) ([]any, error) {
+		v := c.Query(sortKey)
+		if v == "" {
+			return records, nil
+		}
+
+		type sortField struct {
+			path       jsonpath.Path
+			descending bool
+		}
+
+		var fields []sortField
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			descending := strings.HasPrefix(name, "-")
+			name = strings.TrimLeft(name, "+-")
+
+			path, err := jsonpath.Parse(name)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", errInvalidFilter, sortKey)
+			}
+			fields = append(fields, sortField{path: path, descending: descending})
+		}
+
+		sorted := make([]any, len(records))
+		copy(sorted, records)
+
+		sort.SliceStable(sorted, func(i, j int) bool {
+			for _, f := range fields {
+				a, aFound := f.path.Get(sorted[i])
+				b, bFound := f.path.Get(sorted[j])
+
+				// Records without the field come last
+				if aFound != bFound {
+					return aFound
+				}
+
+				result := compareValues(a, jsonvalue.String(b))
+				if result == 0 {
+					continue
+				}
+				if f.descending {
+					return result > 0
+				}
+				return result < 0
+			}
+			return false
+		})
+
+		return sorted, nil
+	}
+}
+
+// projectFields keeps only the fields of the fields parameter in the records
+func projectFields(fieldsKey string) func(c *gin.Context, records []any) []any {
+	return func(c *gin.Context, records []any) []any {
+		v := c.Query(fieldsKey)
+		if v == "" {
+			return records
+		}
+
+		fields := strings.Split(v, ",")
+
+		projected := make([]any, 0, len(records))
+		for _, r := range records {
+			record, ok := r.(map[string]any)
+			if !ok {
+				projected = append(projected, r)
+				continue
+			}
+
+			p := make(map[string]any, len(fields))
+			for _, field := range fields {
+				field = strings.TrimSpace(field)
+				if value, found := record[field]; found {
+					p[field] = value
+				}
+			}
+			projected = append(projected, p)
+		}
+
+		return projected
+	}
+}
+
+// compareValues compares the record value with the given value, numerically
+// when both are numbers or numeric strings, so that "9" sorts before "10"
+func compareValues(actual any, value string) int {
+	n, ok := actual.(float64)
+	if s, isString := actual.(string); isString {
+		var err error
+		n, err = strconv.ParseFloat(s, 64)
+		ok = err == nil
+	}
+
+	if ok {
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			switch {
+			case n < v:
+				return -1
+			case n > v:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	return strings.Compare(jsonvalue.String(actual), value)
+}
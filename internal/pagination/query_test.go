@@ -0,0 +1,53 @@
+package pagination
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"mock-server/internal/config"
+)
+
+func TestQueryFilter(t *testing.T) {
+	records := []any{
+		map[string]any{"id": float64(1), "status": "open", "severity": float64(3)},
+		map[string]any{"id": float64(2), "status": "closed", "severity": float64(10)},
+		map[string]any{"id": float64(3), "status": "open", "severity": float64(10)},
+		map[string]any{"id": float64(1000000), "status": "closed", "severity": float64(1)},
+	}
+
+	tests := []struct {
+		name   string
+		query  config.Query
+		target string
+		ids    []string
+	}{
+		{"field filter", config.Query{Filter: true}, "/records?status=open", []string{"1", "3"}},
+		{"unknown parameter ignored", config.Query{Filter: true}, "/records?starting_after=1", []string{"1", "2", "3", "1000000"}},
+		{"numeric sort", config.Query{Filter: true}, "/records?sort=-severity,id", []string{"2", "3", "1", "1000000"}},
+		{"filter fields only", config.Query{Filter: true, FilterFields: []string{"severity"}}, "/records?status=closed", []string{"1", "2", "3", "1000000"}},
+		{"contains a large number", config.Query{Filter: true}, "/records?id[contains]=100000", []string{"1000000"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := testEndpoint(t, records, config.Pagination{
+				Type:    "offset",
+				Options: map[string]any{"totalRecord": float64(4)},
+			})
+			endpoint.Query = &tt.query
+			p, err := CreatePaginator(endpoint)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			status, body := serve(t, p, "/records", tt.target)
+			if status != http.StatusOK {
+				t.Fatalf("status = %d: %v", status, body)
+			}
+			if got := dataIDs(t, body); !reflect.DeepEqual(got, tt.ids) {
+				t.Errorf("ids = %v, want %v", got, tt.ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/jsonpath"
+	"mock-server/internal/state"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTimelineField = "updated_at"
+	defaultTimelineStep  = time.Minute
+)
+
+var (
+	// defaultTimelineFilters are the filter parameters of a timeline and how they compare
+	defaultTimelineFilters = map[string]string{
+		"since":         "gte",
+		"updated_after": "gt",
+		"start_time":    "gte",
+		"end_time":      "lt",
+	}
+
+	errInvalidFilter = errors.New("invalid filter parameter")
+)
+
+// stage transforms the records of the endpoint for the request before they are paginated
+type stage func(c *gin.Context, records []any) ([]any, error)
+
+// recordSet is the source of the endpoint records with the stages applied on every request
+type recordSet struct {
+	source dataset.Source
+	// sourced tells whether the source was given, e.g. a resource collection,
+	// instead of built from the response file
+	sourced bool
+	mutable *dataset.Mutable
+	// stateful is the dataset built by the record set when it changes between requests
+	stateful state.Stateful
+	stages   []stage
+	// sortKey is the sort parameter of the request, when the endpoint has a query
+	sortKey    string
+	projection func(c *gin.Context, records []any) []any
+}
+
+// load returns the records of the source after all the stages
+func (r recordSet) load(c *gin.Context) ([]any, error) {
+	if r.mutable != nil {
+		r.mutable.Request()
+	}
+
+	records := r.source.Records()
+
+	for _, s := range r.stages {
+		var err error
+		if records, err = s(c, records); err != nil {
+			return nil, err
+		}
+	}
+
+	return records, nil
+}
+
+// pageLimit returns the number of pages served at most. The records of a given
+// source have the pages they fill, whatever the configured totalPage.
+func (r recordSet) pageLimit(p paginationParameters, totalRecords, pageSize int) int {
+	if r.sourced {
+		return max((totalRecords+pageSize-1)/pageSize, 1)
+	}
+	return p.totalPageCount
+}
+
+// project selects the fields of the records of the page
+func (r recordSet) project(c *gin.Context, records []any) []any {
+	if r.projection == nil {
+		return records
+	}
+	return r.projection(c, records)
+}
+
+// datasetPaginator is implemented by the paginators serving a record set
+type datasetPaginator interface {
+	recordSet() *recordSet
+}
+
+// Stateful returns the dataset of the paginator when it changes between
+// requests, i.e. a timeline or a mutable dataset, so that it can be persisted
+func Stateful(p Paginator) (state.Stateful, bool) {
+	d, ok := p.(datasetPaginator)
+	if !ok || d.recordSet() == nil || d.recordSet().stateful == nil {
+		return nil, false
+	}
+	return d.recordSet().stateful, true
+}
+
+// loadRecordSet returns the record set over the given source, or over the dataset
+// built from the response object when the endpoint has no source of its own. The
+// request keys are the parameters of the paginator, which never filter the records.
+func loadRecordSet(endpoint config.Endpoint, source dataset.Source, responseObj any, responseField jsonpath.Path, p paginationParameters, requestKeys ...string) recordSet {
+	r := recordSet{source: source, sourced: source != nil}
+
+	if endpoint.Timeline != nil {
+		field := endpoint.Timeline.Field
+		if field == "" {
+			field = defaultTimelineField
+		}
+
+		if source == nil {
+			templates := arrayAt(responseObj, responseField)
+			timeline := dataset.NewTimeline(templates, p.totalRecordCount, p.idKey, timelineOptions(endpoint.Timeline, field, p.totalRecordCount))
+			r.source, r.stateful = timeline, timeline
+		}
+
+		filters := endpoint.Timeline.Filters
+		if len(filters) == 0 {
+			filters = defaultTimelineFilters
+		}
+		r.stages = append(r.stages, timelineStage(field, filters))
+	}
+
+	if endpoint.Query != nil {
+		reserved := append([]string{}, requestKeys...)
+		if endpoint.Timeline != nil {
+			for param := range endpoint.Timeline.Filters {
+				reserved = append(reserved, param)
+			}
+			if len(endpoint.Timeline.Filters) == 0 {
+				for param := range defaultTimelineFilters {
+					reserved = append(reserved, param)
+				}
+			}
+		}
+		r.stages = append(r.stages, queryStages(endpoint.Query, reserved)...)
+
+		r.sortKey = defaultSortKey
+		if endpoint.Query.SortKey != "" {
+			r.sortKey = endpoint.Query.SortKey
+		}
+
+		fieldsKey := defaultFieldsKey
+		if endpoint.Query.FieldsKey != "" {
+			fieldsKey = endpoint.Query.FieldsKey
+		}
+		r.projection = projectFields(fieldsKey)
+	}
+
+	// Mutations change the dataset of the response file between the requests
+	if r.source == nil && len(endpoint.Mutations) > 0 {
+		templates := arrayAt(responseObj, responseField)
+		r.mutable = dataset.NewMutable(templates, p.totalRecordCount, p.idKey, mutations(endpoint.Mutations))
+		r.source, r.stateful = r.mutable, r.mutable
+	}
+
+	if r.source == nil {
+		r.source = dataset.Static(loadRecords(responseObj, responseField, p))
+	}
+
+	return r
+}
+
+// mutations converts the mutation configs
+func mutations(cfgs []config.Mutation) []dataset.Mutation {
+	mutations := make([]dataset.Mutation, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		mutations = append(mutations, dataset.Mutation{
+			AfterRequest: cfg.AfterRequest,
+			Every:        cfg.Every,
+			Action:       dataset.MutationAction(cfg.Action),
+			Count:        cfg.Count,
+			Position:     dataset.MutationPosition(cfg.Position),
+		})
+	}
+	return mutations
+}
+
+// timelineOptions converts the timeline config, the config is validated on load
+func timelineOptions(timeline *config.Timeline, field string, total int) dataset.TimelineOptions {
+	options := dataset.TimelineOptions{
+		Field: field,
+		Step:  defaultTimelineStep,
+	}
+
+	if step, err := time.ParseDuration(timeline.Step); err == nil {
+		options.Step = step
+	}
+
+	// By default the newest record is stamped with the current time
+	options.Start = time.Now().Add(-time.Duration(total-1) * options.Step)
+	if start, err := time.Parse(time.RFC3339, timeline.Start); err == nil {
+		options.Start = start
+	}
+
+	if timeline.Advance != nil {
+		options.Every, _ = time.ParseDuration(timeline.Advance.Every)
+		options.Records = timeline.Advance.Records
+	}
+
+	return options
+}
+
+// timelineStage keeps the records whose timestamp passes the filter parameters of the request
+func timelineStage(field string, filters map[string]string) stage {
+	return func(c *gin.Context, records []any) ([]any, error) {
+		type bound struct {
+			operator string
+			value    time.Time
+		}
+
+		var bounds []bound
+		for param, operator := range filters {
+			v := c.Query(param)
+			if v == "" {
+				continue
+			}
+			value, err := parseTimestamp(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", errInvalidFilter, param)
+			}
+			bounds = append(bounds, bound{operator: operator, value: value})
+		}
+
+		if len(bounds) == 0 {
+			return records, nil
+		}
+
+		// Records without a timestamp, e.g. resource records created without it, are always visible
+		filtered := make([]any, 0, len(records))
+		for _, r := range records {
+			record, ok := r.(map[string]any)
+			if !ok {
+				filtered = append(filtered, r)
+				continue
+			}
+			s, _ := record[field].(string)
+			stamp, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				filtered = append(filtered, record)
+				continue
+			}
+
+			matches := true
+			for _, b := range bounds {
+				switch b.operator {
+				case "gt":
+					matches = stamp.After(b.value)
+				case "gte":
+					matches = !stamp.Before(b.value)
+				case "lt":
+					matches = stamp.Before(b.value)
+				case "lte":
+					matches = !stamp.After(b.value)
+				}
+				if !matches {
+					break
+				}
+			}
+
+			if matches {
+				filtered = append(filtered, record)
+			}
+		}
+
+		return filtered, nil
+	}
+}
+
+// parseTimestamp parses a RFC 3339 timestamp or unix seconds
+func parseTimestamp(v string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,35 @@
+package pagination
+
+import (
+	"sync"
+
+	"mock-server/internal/config"
+)
+
+// sequence serves the pages of a sequential endpoint in order, one page per
+// request, whatever page the request asks for
+type sequence struct {
+	mu      sync.Mutex
+	pages   int
+	records int
+}
+
+// loadSequence returns the sequence of the endpoint, nil when the page is
+// selected by the request
+func loadSequence(endpoint config.Endpoint) *sequence {
+	if sequential, _ := endpoint.Pagination.Options["sequential"].(bool); sequential {
+		return &sequence{}
+	}
+	return nil
+}
+
+// next returns the number of the next page and the offset of its first record
+func (s *sequence) next(pageSize int) (pageNumber, start int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pageNumber, start = s.pages+1, s.records
+	s.pages++
+	s.records += pageSize
+	return pageNumber, start
+}
@@ -0,0 +1,241 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/jsonpath"
+	"mock-server/internal/jsonvalue"
+	"mock-server/internal/template"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sseEndpoint        = "sse"
+	defaultSSEInterval = time.Second
+
+	closeDisconnect = "close"
+	abortDisconnect = "abort"
+)
+
+// ssePaginator streams the endpoint dataset as Server-Sent Events, one record per event
+type ssePaginator struct {
+	interval              time.Duration
+	event                 string
+	eventField            *jsonpath.Path
+	idField               string
+	retry                 int
+	keepOpen              bool
+	heartbeat             time.Duration
+	disconnectAfter       int
+	disconnectProbability float64
+	disconnectMode        string
+	records               recordSet
+}
+
+var _ Paginator = (*ssePaginator)(nil)
+
+// createSSEPaginator creates a new SSE paginator for the given endpoint
+func createSSEPaginator(endpoint config.Endpoint, source dataset.Source) (*ssePaginator, error) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("creating sse paginator", map[string]any{"endpoint": endpoint.Path})
+
+	parameters := loadPaginationParameters(endpoint)
+
+	s := ssePaginator{
+		interval:       defaultSSEInterval,
+		idField:        parameters.idKey,
+		disconnectMode: closeDisconnect,
+	}
+
+	if cfg := endpoint.SSE; cfg != nil {
+		s.event = cfg.Event
+		s.retry = cfg.Retry
+		s.keepOpen = cfg.KeepOpen
+		s.disconnectAfter = cfg.DisconnectAfter
+		s.disconnectProbability = cfg.DisconnectProbability
+
+		if cfg.Interval != "" {
+			s.interval, _ = time.ParseDuration(cfg.Interval)
+		}
+		if cfg.Heartbeat != "" {
+			s.heartbeat, _ = time.ParseDuration(cfg.Heartbeat)
+		}
+		if cfg.IDField != "" {
+			s.idField = cfg.IDField
+		}
+		if cfg.DisconnectMode != "" {
+			s.disconnectMode = cfg.DisconnectMode
+		}
+		if cfg.EventField != "" {
+			path, err := jsonpath.Parse(cfg.EventField)
+			if err != nil {
+				mockLogger.Warn("invalid sse event field", err)
+				return nil, err
+			}
+			s.eventField = &path
+		}
+	}
+
+	responseObj, err := loadResponseObj(endpoint.ResponseObjFilePath)
+	if err != nil {
+		errInvalidResponse := fmt.Errorf("invalid response file path for endpoint: %s", endpoint.Path)
+		mockLogger.Warn(errInvalidResponse.Error(), err)
+		return nil, errors.Join(errInvalidResponse, err)
+	}
+
+	responseField, err := findResponseField(endpoint, responseObj)
+	if err != nil {
+		mockLogger.Warn(err.Error(), err)
+		return nil, err
+	}
+
+	// Without a configured record count the stream holds the records of the file
+	if _, ok := intOption(endpoint.Pagination.Options, "totalRecord"); !ok {
+		parameters.totalRecordCount = len(arrayAt(responseObj, responseField))
+	}
+
+	s.records = loadRecordSet(endpoint, source, responseObj, responseField, parameters, "lastEventId")
+
+	return &s, nil
+}
+
+// Paginate is the handler function for the SSE paginator. A client sending the
+// Last-Event-ID header resumes after the record of that id.
+func (s *ssePaginator) Paginate(c *gin.Context) {
+	records, err := s.records.load(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start := 0
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+	if lastEventID != "" {
+		for i, record := range records {
+			if s.eventID(i, record) == lastEventID {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	// The stream outlives the write timeout of the server
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	if s.retry > 0 {
+		fmt.Fprintf(c.Writer, "retry: %d\n\n", s.retry)
+	}
+	c.Writer.Flush()
+
+	sent := 0
+	for i := start; i < len(records); i++ {
+		if sent > 0 && !s.wait(c, s.interval) {
+			return
+		}
+
+		record := template.Render(s.records.project(c, []any{records[i]}), c).([]any)[0]
+		c.Render(-1, sse.Event{Id: s.eventID(i, records[i]), Event: s.eventName(record), Data: record})
+		c.Writer.Flush()
+		sent++
+
+		if (s.disconnectAfter > 0 && sent >= s.disconnectAfter) ||
+			(s.disconnectProbability > 0 && rand.Float64() < s.disconnectProbability) {
+			s.disconnect(c)
+			return
+		}
+	}
+
+	// After the last record the stream stays open, with its heartbeats, until the client leaves
+	for s.keepOpen && s.wait(c, time.Hour) {
+	}
+}
+
+func (s *ssePaginator) recordSet() *recordSet {
+	return &s.records
+}
+
+// wait waits the given duration, sending heartbeat comments meanwhile. It
+// returns false when the client has left.
+func (s *ssePaginator) wait(c *gin.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	var heartbeat <-chan time.Time
+	if s.heartbeat > 0 {
+		ticker := time.NewTicker(s.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-timer.C:
+			return true
+		case <-heartbeat:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// disconnect ends the stream. The abort mode drops the connection without
+// ending the response, like a network failure would.
+func (s *ssePaginator) disconnect(c *gin.Context) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("sse disconnect injected", map[string]any{"path": c.Request.URL.Path, "mode": s.disconnectMode})
+
+	if s.disconnectMode != abortDisconnect {
+		return
+	}
+
+	conn, _, err := http.NewResponseController(c.Writer).Hijack()
+	if err != nil {
+		// HTTP/2 connections can not be hijacked, the stream is closed instead
+		mockLogger.Warn("failed to abort sse connection", err)
+		return
+	}
+	conn.Close()
+	c.Abort()
+}
+
+// eventID returns the id of the record's event, its id field or else its position
+func (s *ssePaginator) eventID(i int, record any) string {
+	if obj, ok := record.(map[string]any); ok {
+		if id, found := obj[s.idField]; found && id != nil {
+			return dataset.IDString(id)
+		}
+	}
+	return strconv.Itoa(i + 1)
+}
+
+// eventName returns the event type of the record, empty for the default message type
+func (s *ssePaginator) eventName(record any) string {
+	if s.eventField != nil {
+		if value, found := s.eventField.Get(record); found && value != nil {
+			return jsonvalue.String(value)
+		}
+	}
+	return s.event
+}
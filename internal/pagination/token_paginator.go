@@ -1,35 +1,45 @@
 package pagination
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"reflect"
-	"strconv"
+	"strings"
 
 	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/jsonpath"
 	"mock-server/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
-// pagePaginator responsible for the page based pagination
+// tokenPaginator responsible for the token based pagination
 type tokenPaginator struct {
-	responseObj           map[string]interface{}
-	tokenLocation         pageParameterLocation
-	responseField         string
-	paginationParameters  paginationParameters
+	responseObj          any
+	tokenLocation        pageParameterLocation
+	tokenParam           string
+	prevTokenKey         string
+	responseField        jsonpath.Path
+	paginationParameters paginationParameters
+	records              recordSet
+	metadata             metadata
+	sequence             *sequence
 }
 
-// createPagePaginator creates a new page paginator for the given endpoint
-func createTokenPaginator(endpoint config.Endpoint) (*tokenPaginator, error) {
+// createTokenPaginator creates a new token paginator for the given endpoint
+func createTokenPaginator(endpoint config.Endpoint, source dataset.Source) (*tokenPaginator, error) {
 	var mockLogger = logger.GetLogger()
 
-	mockLogger.InfoW("creating page paginator", map[string]any{"endpoint": endpoint.Path})
+	mockLogger.InfoW("creating token paginator", map[string]any{"endpoint": endpoint.Path})
 
 	t := tokenPaginator{
 		tokenLocation: pageParameterLocation(endpoint.Pagination.Location),
+		prevTokenKey:  defaultPrevTokenKey,
+	}
+
+	if prevTokenKey, ok := endpoint.Pagination.Options["prevTokenKey"].(string); ok {
+		t.prevTokenKey = prevTokenKey
 	}
 	responseObj, err := loadResponseObj(endpoint.ResponseObjFilePath)
 	if err != nil {
@@ -42,114 +52,97 @@ func createTokenPaginator(endpoint config.Endpoint) (*tokenPaginator, error) {
 
 	t.paginationParameters = loadPaginationParameters(endpoint)
 
-	// Validate the response field
-	if endpoint.ResponseField != "" {
-		_, ok := responseObj[endpoint.ResponseField].([]any)
-		if !ok {
-			errInvalidResponseField := fmt.Errorf("invalid response field for endpoint: %v", endpoint.Path)
-			mockLogger.Warn(errInvalidResponseField.Error(), err)
-			return nil, errors.Join(errInvalidResponseField, err)
-		}
-		t.responseField = endpoint.ResponseField
-		return &t, nil
+	// The token is sent back in the parameter named like the response field, or
+	// in the token parameter when the field is a JSON path or an XPath
+	t.tokenParam = t.paginationParameters.tokenKey
+	if strings.ContainsAny(t.tokenParam, "$.[/") {
+		t.tokenParam = defaultTokenKey
+	}
+	if tokenParam, ok := endpoint.Pagination.Options["tokenParam"].(string); ok {
+		t.tokenParam = tokenParam
 	}
 
-	if endpoint.ResponseField == "" {
-		for k, v := range responseObj {
-			if _, ok := v.([]interface{}); ok {
-				t.responseField = k
-				return &t, nil
-			}
-		}
+	t.metadata, err = loadMetadata(endpoint)
+	if err != nil {
+		mockLogger.Warn(err.Error(), err)
+		return nil, err
 	}
 
-	errInvalidResponseField := fmt.Errorf("response field not present in response object for endpoint: %v", endpoint.Path)
-	mockLogger.Warn(errInvalidResponseField.Error(), err)
-	return nil, errors.Join(errInvalidResponseField, err)
-}
+	// Validate the response field, or find the array field when the user not specified it
+	t.responseField, err = findResponseField(endpoint, responseObj)
+	if err != nil {
+		mockLogger.Warn(err.Error(), err)
+		return nil, err
+	}
 
-// Paginate is the handler function for the page paginator
-func (t *tokenPaginator) Paginate(c *gin.Context) {
+	t.records = loadRecordSet(endpoint, source, responseObj, t.responseField, t.paginationParameters, t.tokenParam, t.prevTokenKey, t.paginationParameters.pageSizeKey)
 
-	var pageSize = defaultPageSize
+	t.sequence = loadSequence(endpoint)
 
-	if t.paginationParameters.pageSentCount >= t.paginationParameters.totalPageCount {
-		c.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
-	}
+	return &t, nil
+}
+
+// Paginate is the handler function for the token paginator
+func (t *tokenPaginator) Paginate(c *gin.Context) {
 
 	// Extract pagination params from the respective location
-	switch t.tokenLocation {
-	case body:
-		var requestBody map[string]interface{}
-		err := c.ShouldBindJSON(&requestBody)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse request body"})
-			return
-		}
-		value, found := requestBody[t.paginationParameters.pageSizeKey]
-		if found {
-			switch v := value.(type) {
-			case float64:
-				pageSize = int(v)
-			case int:
-				// Already an integer
-				pageSize = v
-			case int32, int64:
-				// Handle other integer types
-				pageSize = int(reflect.ValueOf(v).Int())
-			case uint, uint32, uint64:
-				// Handle unsigned integers
-				pageSize = int(reflect.ValueOf(v).Uint())
-			default:
-				c.JSON(http.StatusBadRequest, gin.H{"error": "size must be a number"})
-				return
-			}
-		}
+	pageSize, err := requestIntParameter(c, t.tokenLocation, t.paginationParameters.pageSizeKey, t.paginationParameters.pageSize)
+	if err != nil || pageSize <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be a number"})
+		return
+	}
 
-	case header:
-		if v := c.GetHeader(t.paginationParameters.pageSizeKey); v != "" {
-			if p, err := strconv.Atoi(v); err == nil && p > 0 {
-				pageSize = p
-			}
-		}
-	case query:
-		size, err := strconv.Atoi(c.DefaultQuery(t.paginationParameters.pageSizeKey, strconv.Itoa(defaultPageSize)))
+	start := 0
+	if t.sequence != nil {
+		_, start = t.sequence.next(pageSize)
+	} else if v, found := requestParameter(c, t.tokenLocation, t.tokenParam); found && v != "" {
+		start, err = DecodeCursor(v)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sizeValue"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
 			return
 		}
-		pageSize = size
 	}
 
-	// 3. Find the response object
-	arr, ok := t.responseObj[t.responseField].([]any)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid response field"})
+	allRecords, err := t.records.load(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	object := arr[0]
-	APIResponseObject := make([]any, 0, pageSize)
-
-	if t.paginationParameters.sentRecordsCount+pageSize > t.paginationParameters.totalRecordCount {
-		pageSize = t.paginationParameters.totalRecordCount - t.paginationParameters.sentRecordsCount
+	pageLimit := t.records.pageLimit(t.paginationParameters, len(allRecords), pageSize)
+	if start/pageSize >= pageLimit {
+		c.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
+		return
 	}
+	records := dataset.Window(allRecords, start, pageSize)
 
-	for len(APIResponseObject) < pageSize {
-		APIResponseObject = append(APIResponseObject, object)
+	// The last page has no next token and the first page no previous token
+	var nextToken, prevToken any
+	next := start + len(records)
+	if next < len(allRecords) && (next/pageSize) < pageLimit {
+		nextToken = EncodeCursor(next)
+	}
+	if start > 0 {
+		prevToken = EncodeCursor(max(start-pageSize, 0))
 	}
 
-	t.paginationParameters.pageSentCount++
-	t.paginationParameters.sentRecordsCount += pageSize
+	info := newPageInfo(start, pageSize, len(records), len(allRecords), pageLimit)
+	info.nextCursor = nextToken
+	info.prevCursor = prevToken
 
-	t.responseObj[t.responseField] = APIResponseObject
+	fields := map[string]any{
+		t.paginationParameters.tokenKey: nextToken,
+		t.prevTokenKey:                  prevToken,
+	}
 
-	jsonResponse, err := json.Marshal(t.responseObj)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create response object"})
-		return
+	// A sequential endpoint keeps the tokens of the response file
+	if t.sequence != nil {
+		fields = nil
 	}
 
-	c.Data(http.StatusOK, "application/json", jsonResponse)
+	writeResponse(c, t.responseObj, t.responseField, t.records.project(c, records), fields, t.metadata, info)
+}
 
+func (t *tokenPaginator) recordSet() *recordSet {
+	return &t.records
 }
@@ -0,0 +1,74 @@
+package pagination
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"mock-server/internal/config"
+)
+
+func TestTokenPaginatorWalk(t *testing.T) {
+	endpoint := testEndpoint(t, testRecords(5), config.Pagination{
+		Type:    "token",
+		Options: map[string]any{"totalRecord": float64(5), "pageSize": float64(2), "totalPage": float64(3)},
+	})
+	p, err := CreatePaginator(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	target := "/records"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("the last page has a next token")
+		}
+
+		status, body := serve(t, p, "/records", target)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d: %v", status, body)
+		}
+		if pages == 0 && body["prevToken"] != nil {
+			t.Errorf("first page prevToken = %v, want null", body["prevToken"])
+		}
+		ids = append(ids, dataIDs(t, body)...)
+
+		next, ok := body["token"].(string)
+		if !ok {
+			break
+		}
+		target = "/records?token=" + next
+	}
+
+	if want := []string{"1", "2", "3", "4", "5"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+
+	if status, _ := serve(t, p, "/records", "/records?token=invalid"); status != http.StatusBadRequest {
+		t.Errorf("invalid token status = %d, want 400", status)
+	}
+}
+
+func TestTokenPaginatorPathTokenKey(t *testing.T) {
+	endpoint := testEndpoint(t, testRecords(4), config.Pagination{
+		Type:    "token",
+		Options: map[string]any{"totalRecord": float64(4), "pageSize": float64(2), "tokenKey": "$.meta.next"},
+	})
+	p, err := CreatePaginator(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, body := serve(t, p, "/records", "/records")
+	next, ok := body["meta"].(map[string]any)["next"].(string)
+	if !ok {
+		t.Fatalf("no next token in %v", body)
+	}
+
+	// The token of a path token key is sent back in the token parameter
+	_, body = serve(t, p, "/records", "/records?token="+next)
+	if got := dataIDs(t, body); !reflect.DeepEqual(got, []string{"3", "4"}) {
+		t.Errorf("ids = %v, want [3 4]", got)
+	}
+}
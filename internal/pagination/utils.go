@@ -3,11 +3,24 @@ package pagination
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/jsonpath"
+	"mock-server/internal/jsonvalue"
+	"mock-server/internal/template"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 const (
@@ -16,11 +29,20 @@ const (
 	defaultOffsetKey        = "offset"
 	defaultLimitKey         = "limit"
 	defaultLinkKey          = "link"
+	defaultPrevLinkKey      = "prevLink"
+	defaultTokenKey         = "token"
+	defaultPrevTokenKey     = "prevToken"
+	defaultIDKey            = "id"
 	defaultPageSize         = 100
 	defaultPageCount        = 2
 	defaultTotalRecordCount = 200
 )
 
+var (
+	errNotANumber  = errors.New("pagination parameter must be a number")
+	errNotJSONFile = errors.New("response file is not a JSON file")
+)
+
 // loadPaginationParameters loads the pagination parameters
 func loadPaginationParameters(endpoint config.Endpoint) (p paginationParameters) {
 	p = paginationParameters{}
@@ -31,8 +53,9 @@ func loadPaginationParameters(endpoint config.Endpoint) (p paginationParameters)
 	p.pageSize = defaultPageSize
 	p.pageKey = defaultPageKey
 	p.pageSizeKey = defaultPageSizeKey
-	p.pageSentCount = 0
-	p.sentRecordsCount = 0
+	p.offsetKey = defaultOffsetKey
+	p.tokenKey = defaultTokenKey
+	p.idKey = defaultIDKey
 
 	if pageKey, ok := endpoint.Pagination.Options["pageKey"].(string); ok {
 		p.pageKey = pageKey
@@ -42,15 +65,27 @@ func loadPaginationParameters(endpoint config.Endpoint) (p paginationParameters)
 		p.pageSizeKey = pageSizeKey
 	}
 
-	if pageSize, ok := endpoint.Pagination.Options["pageSize"].(int); ok {
+	if offsetKey, ok := endpoint.Pagination.Options["offsetKey"].(string); ok {
+		p.offsetKey = offsetKey
+	}
+
+	if tokenKey, ok := endpoint.Pagination.Options["tokenKey"].(string); ok {
+		p.tokenKey = tokenKey
+	}
+
+	if idKey, ok := endpoint.Pagination.Options["idKey"].(string); ok {
+		p.idKey = idKey
+	}
+
+	if pageSize, ok := intOption(endpoint.Pagination.Options, "pageSize"); ok {
 		p.pageSize = pageSize
 	}
 
-	if pageCount, ok := endpoint.Pagination.Options["totalPage"].(int); ok {
+	if pageCount, ok := intOption(endpoint.Pagination.Options, "totalPage"); ok {
 		p.totalPageCount = pageCount
 	}
 
-	if totalRecordCount, ok := endpoint.Pagination.Options["totalRecord"].(int); ok {
+	if totalRecordCount, ok := intOption(endpoint.Pagination.Options, "totalRecord"); ok {
 		p.totalRecordCount = totalRecordCount
 	}
 
@@ -59,32 +94,298 @@ func loadPaginationParameters(endpoint config.Endpoint) (p paginationParameters)
 	return p
 }
 
-// loadResponseObj loads the response object from the given file path
-func loadResponseObj(path string) (map[string]interface{}, error) {
-	if path == "" {
-		return nil, errors.New("empty file path")
+// intOption returns the integer value of the option, JSON numbers are decoded as float64
+func intOption(options map[string]any, key string) (int, bool) {
+	switch v := options[key].(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	default:
+		return 0, false
 	}
+}
 
-	// Clean the path (removes ./ ../ etc.)
-	cleanPath := filepath.Clean(path)
+// loadRecords builds the dataset of the endpoint from the array at the response field
+func loadRecords(responseObj any, responseField jsonpath.Path, p paginationParameters) []any {
+	templates := arrayAt(responseObj, responseField)
+	return dataset.Build(templates, p.totalRecordCount, p.idKey)
+}
 
-	responseObjFilePath := filepath.Join(".././", cleanPath)
+// LoadRecords loads the dataset of the given endpoint from its response file
+func LoadRecords(endpoint config.Endpoint) ([]any, error) {
+	responseObj, err := loadResponseObj(endpoint.ResponseObjFilePath)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("invalid response file path for endpoint: %s", endpoint.Path), err)
+	}
 
-	file, err := os.Open(responseObjFilePath)
+	responseField, err := findResponseField(endpoint, responseObj)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	bytes, err := io.ReadAll(file)
+	parameters := loadPaginationParameters(endpoint)
+
+	// Without a configured record count the dataset holds the records of the file
+	if _, ok := intOption(endpoint.Pagination.Options, "totalRecord"); !ok {
+		parameters.totalRecordCount = len(arrayAt(responseObj, responseField))
+	}
+
+	return loadRecords(responseObj, responseField, parameters), nil
+}
+
+// LoadResponse loads the response object of the given endpoint from its response
+// file, or the value at its response field when it is set
+func LoadResponse(endpoint config.Endpoint) (any, error) {
+	responseObj, err := loadResponseObj(endpoint.ResponseObjFilePath)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("invalid response file path for endpoint: %s", endpoint.Path), err)
+	}
+
+	if endpoint.ResponseField == "" {
+		return responseObj, nil
+	}
+
+	value, found := jsonpath.Get(responseObj, endpoint.ResponseField)
+	if !found {
+		return nil, fmt.Errorf("invalid response field for endpoint: %v", endpoint.Path)
+	}
+
+	return value, nil
+}
+
+// requestParameter returns the value of the pagination parameter from the given location
+func requestParameter(c *gin.Context, location pageParameterLocation, key string) (string, bool) {
+	switch location {
+	case body:
+		var requestBody map[string]interface{}
+		if err := c.ShouldBindBodyWith(&requestBody, binding.JSON); err != nil {
+			return "", false
+		}
+		value, found := requestBody[key]
+		if !found || value == nil {
+			return "", false
+		}
+		if s, ok := value.(string); ok {
+			return s, true
+		}
+		if f, ok := value.(float64); ok && f == math.Trunc(f) {
+			return strconv.FormatInt(int64(f), 10), true
+		}
+		data, _ := json.Marshal(value)
+		return string(data), true
+	case header:
+		v := c.GetHeader(key)
+		return v, v != ""
+	default:
+		return c.GetQuery(key)
+	}
+}
+
+// requestIntParameter returns the integer value of the pagination parameter, or
+// the default value when the request does not carry it
+func requestIntParameter(c *gin.Context, location pageParameterLocation, key string, defaultValue int) (int, error) {
+	v, found := requestParameter(c, location, key)
+	if !found || v == "" {
+		return defaultValue, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, errors.Join(errNotANumber, err)
+	}
+
+	return n, nil
+}
+
+// writeResponse renders the response object with the page of records and writes it
+func writeResponse(c *gin.Context, responseObj any, responseField jsonpath.Path, records []any, fields map[string]any, meta metadata, info pageInfo) {
+	if x, ok := responseObj.(*xmlResponse); ok {
+		x.write(c, records, fields, meta, info)
+		return
+	}
+
+	var response any
+	if responseField.IsRoot() {
+		// A top level array has no room for the cursors, they are sent as headers
+		response = template.Render(records, c)
+		for k, v := range fields {
+			if v != nil {
+				c.Header(k, jsonvalue.String(v))
+			}
+		}
+	} else {
+		response = template.Render(responseObj, c)
+		_ = responseField.Set(response, template.Render(records, c))
+
+		// The field keys are JSON paths, e.g. the token key pagination.next_token
+		for k, v := range fields {
+			if path, err := jsonpath.Parse(k); err == nil {
+				_ = path.Set(response, v)
+			}
+		}
+	}
+
+	meta.apply(c, response, info)
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create response object"})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", jsonResponse)
+}
+
+// loadResponseObj loads the JSON response object from the given file path, the
+// root of the object may be an array. XML files are loaded as an xmlResponse.
+func loadResponseObj(path string) (any, error) {
+	if isXMLFile(path) {
+		return loadXMLResponse(path)
+	}
+	if !isJSONFile(path) {
+		return nil, fmt.Errorf("%w: %s", errNotJSONFile, path)
+	}
+
+	bytes, err := loadResponseFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var responseObj map[string]interface{}
+	var responseObj any
 	if err := json.Unmarshal(bytes, &responseObj); err != nil {
 		return nil, err
 	}
 
 	return responseObj, nil
 }
+
+// loadResponseFile reads the response file from the given file path
+func loadResponseFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("empty file path")
+	}
+
+	file, err := os.Open(config.ResolveFilePath(path))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// isJSONFile tells whether the response file holds a JSON body, the other
+// files like text, XML or images are served as they are
+func isJSONFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == "" || ext == ".json"
+}
+
+// findResponseField returns the path of the response field of the endpoint, or of
+// the first array field of the response object when the endpoint does not specify
+// it. The top level fields are searched before the nested objects.
+func findResponseField(endpoint config.Endpoint, responseObj any) (jsonpath.Path, error) {
+	if x, ok := responseObj.(*xmlResponse); ok {
+		return x.selectRecords(endpoint)
+	}
+
+	if endpoint.ResponseField != "" {
+		path, err := jsonpath.Parse(endpoint.ResponseField)
+		if err != nil {
+			return jsonpath.Path{}, errors.Join(fmt.Errorf("invalid response field for endpoint: %v", endpoint.Path), err)
+		}
+		if value, found := path.Get(responseObj); !found || !isArray(value) {
+			return jsonpath.Path{}, fmt.Errorf("invalid response field for endpoint: %v", endpoint.Path)
+		}
+		return path, nil
+	}
+
+	switch obj := responseObj.(type) {
+	case []any:
+		// A top level array is paginated as the list itself
+		return jsonpath.Parse("$")
+	case map[string]any:
+		if field, found := findArrayField(obj, "$"); found {
+			return jsonpath.Parse(field)
+		}
+	}
+
+	return jsonpath.Path{}, fmt.Errorf("response field not present in response object for endpoint: %v", endpoint.Path)
+}
+
+// findArrayField searches the object breadth first for an array field and
+// returns its path. The keys are visited in order, so that the result is stable.
+func findArrayField(obj map[string]interface{}, prefix string) (string, bool) {
+	type level struct {
+		obj    map[string]interface{}
+		prefix string
+	}
+
+	queue := []level{{obj: obj, prefix: prefix}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		keys := make([]string, 0, len(current.obj))
+		for k := range current.obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if isArray(current.obj[k]) {
+				return fieldPath(current.prefix, k), true
+			}
+		}
+
+		for _, k := range keys {
+			if nested, ok := current.obj[k].(map[string]interface{}); ok {
+				queue = append(queue, level{obj: nested, prefix: fieldPath(current.prefix, k)})
+			}
+		}
+	}
+
+	return "", false
+}
+
+// fieldPath appends the key to the JSON path, keys with special characters are quoted
+func fieldPath(prefix, key string) string {
+	if strings.ContainsAny(key, ".[]'") {
+		return fmt.Sprintf(`%s["%s"]`, prefix, key)
+	}
+	return prefix + "." + key
+}
+
+// arrayAt returns the array at the path of the response object
+func arrayAt(responseObj any, path jsonpath.Path) []any {
+	if x, ok := responseObj.(*xmlResponse); ok {
+		return x.records
+	}
+
+	value, _ := path.Get(responseObj)
+	arr, _ := value.([]any)
+	return arr
+}
+
+// hasField tells whether the response object has the field of the given path,
+// an XPath for XML responses
+func hasField(responseObj any, path string) bool {
+	if x, ok := responseObj.(*xmlResponse); ok {
+		return x.hasElement(path)
+	}
+
+	_, found := jsonpath.Get(responseObj, path)
+	return found
+}
+
+// isTopLevelArray tells whether the records are the root of a JSON response
+func isTopLevelArray(responseObj any, responseField jsonpath.Path) bool {
+	_, ok := responseObj.([]any)
+	return ok && responseField.IsRoot()
+}
+
+func isArray(value any) bool {
+	_, ok := value.([]interface{})
+	return ok
+}
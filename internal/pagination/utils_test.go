@@ -0,0 +1,54 @@
+package pagination
+
+import "testing"
+
+func TestFindArrayField(t *testing.T) {
+	tests := []struct {
+		name  string
+		obj   map[string]any
+		want  string
+		found bool
+	}{
+		{
+			name:  "top level",
+			obj:   map[string]any{"meta": map[string]any{}, "data": []any{}},
+			want:  "$.data",
+			found: true,
+		},
+		{
+			name: "shallowest nested array",
+			obj: map[string]any{
+				"a": map[string]any{"b": map[string]any{"deep": []any{}}},
+				"z": map[string]any{"items": []any{}},
+			},
+			want:  "$.z.items",
+			found: true,
+		},
+		{
+			name:  "first key of a level",
+			obj:   map[string]any{"b": []any{}, "a": []any{}},
+			want:  "$.a",
+			found: true,
+		},
+		{
+			name:  "key with a dot",
+			obj:   map[string]any{"page.items": []any{}},
+			want:  `$["page.items"]`,
+			found: true,
+		},
+		{
+			name:  "no array",
+			obj:   map[string]any{"a": map[string]any{"b": "c"}},
+			found: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := findArrayField(tt.obj, "$")
+			if found != tt.found || got != tt.want {
+				t.Errorf("findArrayField() = %q, %v, want %q, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package pagination
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mock-server/internal/config"
+	"mock-server/internal/jsonpath"
+	"mock-server/internal/jsonvalue"
+	"mock-server/internal/template"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// xmlAttributePrefix marks the record fields holding the attributes of the element
+	xmlAttributePrefix = "@"
+	// xmlTextField is the record field holding the text of an element with attributes
+	xmlTextField = "#text"
+)
+
+var (
+	errInvalidXMLResponse = errors.New("invalid xml response")
+)
+
+// xmlResponse is an XML response file. The elements selected by the XPath of the
+// response field are the records; they are converted to JSON like records so that
+// the datasets, filters and cursors work the same as for JSON responses.
+type xmlResponse struct {
+	document   []byte
+	recordPath string
+	records    []any
+}
+
+// isXMLFile tells whether the response file holds an XML body
+func isXMLFile(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".xml"
+}
+
+// loadXMLResponse loads the XML response file from the given file path
+func loadXMLResponse(path string) (*xmlResponse, error) {
+	document, err := loadResponseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := xmlquery.Parse(bytes.NewReader(document)); err != nil {
+		return nil, errors.Join(errInvalidXMLResponse, err)
+	}
+
+	return &xmlResponse{document: document}, nil
+}
+
+// selectRecords selects the paginated elements of the response by the XPath of
+// the response field and converts them to records
+func (x *xmlResponse) selectRecords(endpoint config.Endpoint) (jsonpath.Path, error) {
+	if endpoint.ResponseField == "" {
+		return jsonpath.Path{}, fmt.Errorf("%w: the response field XPath is required for endpoint: %v", errInvalidXMLResponse, endpoint.Path)
+	}
+
+	doc, _ := xmlquery.Parse(bytes.NewReader(x.document))
+	elements, err := xmlquery.QueryAll(doc, endpoint.ResponseField)
+	if err != nil {
+		return jsonpath.Path{}, errors.Join(fmt.Errorf("%w: invalid response field for endpoint: %v", errInvalidXMLResponse, endpoint.Path), err)
+	}
+	if len(elements) == 0 {
+		return jsonpath.Path{}, fmt.Errorf("%w: the response field selects no element for endpoint: %v", errInvalidXMLResponse, endpoint.Path)
+	}
+
+	x.recordPath = endpoint.ResponseField
+	x.records = make([]any, 0, len(elements))
+	for _, element := range elements {
+		x.records = append(x.records, elementRecord(element))
+	}
+
+	return jsonpath.Parse("$")
+}
+
+// hasElement tells whether the element of the XPath, or its parent, exists in the response
+func (x *xmlResponse) hasElement(expr string) bool {
+	if _, err := xpath.Compile(expr); err != nil {
+		return false
+	}
+
+	doc, _ := xmlquery.Parse(bytes.NewReader(x.document))
+	return findOrCreateElement(doc, expr) != nil
+}
+
+// write renders the response with the page of records in place of the selected
+// elements, and the fields and the metadata written into the elements of their XPaths
+func (x *xmlResponse) write(c *gin.Context, records []any, fields map[string]any, meta metadata, info pageInfo) {
+	doc, _ := xmlquery.Parse(bytes.NewReader(x.document))
+	elements, _ := xmlquery.QueryAll(doc, x.recordPath)
+
+	// The document is indented again on output, the whitespace of the file would leave gaps
+	removeWhitespace(doc)
+
+	// The page replaces the selected elements, at the place of the first one
+	first := elements[0]
+	parent, anchor := first.Parent, first.PrevSibling
+	for _, element := range elements {
+		if element == anchor {
+			anchor = element.PrevSibling
+		}
+		xmlquery.RemoveFromTree(element)
+	}
+	for _, record := range records {
+		element := recordElement(first, record)
+		insertAfter(parent, anchor, element)
+		anchor = element
+	}
+
+	// The field and metadata keys are XPaths, e.g. the token key /ListBucketResult/NextContinuationToken
+	values := make(map[string]any, len(fields)+len(meta.body))
+	for expr, value := range fields {
+		values[expr] = value
+	}
+	for _, field := range meta.body {
+		values[field.path.String()] = facts[field.fact](info)
+	}
+	for expr, value := range values {
+		element := findOrCreateElement(doc, expr)
+		if element == nil {
+			continue
+		}
+		// A missing cursor, like the next token of the last page, removes the element
+		if value == nil {
+			xmlquery.RemoveFromTree(element)
+			continue
+		}
+		setText(element, jsonvalue.String(value))
+	}
+
+	meta.applyHeaders(c, info)
+
+	body := doc.OutputXMLWithOptions(xmlquery.WithOutputSelf(), xmlquery.WithIndentation("  "))
+	c.Data(http.StatusOK, "application/xml", []byte(template.RenderXML(body, c)))
+}
+
+// elementRecord converts the element to a record. The child elements become
+// fields, repeated child elements an array and the attributes fields prefixed with @.
+func elementRecord(element *xmlquery.Node) any {
+	record := map[string]any{}
+
+	for _, attr := range element.Attr {
+		record[xmlAttributePrefix+attrName(attr)] = xmlValue(attr.Value)
+	}
+
+	hasChildren := false
+	for child := element.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != xmlquery.ElementNode {
+			continue
+		}
+		hasChildren = true
+
+		name := elementName(child)
+		value := elementRecord(child)
+		switch existing := record[name].(type) {
+		case nil:
+			record[name] = value
+		case []any:
+			record[name] = append(existing, value)
+		default:
+			record[name] = []any{existing, value}
+		}
+	}
+
+	if hasChildren {
+		return record
+	}
+
+	text := xmlValue(strings.TrimSpace(element.InnerText()))
+	if len(record) == 0 {
+		return text
+	}
+	record[xmlTextField] = text
+
+	return record
+}
+
+// recordElement converts the record back to an element shaped like the given
+// element. The fields follow the order of its children, the new fields come last.
+func recordElement(shape *xmlquery.Node, value any) *xmlquery.Node {
+	element := &xmlquery.Node{Type: xmlquery.ElementNode, Data: shape.Data, Prefix: shape.Prefix, NamespaceURI: shape.NamespaceURI}
+
+	record, ok := value.(map[string]any)
+	if !ok {
+		setText(element, jsonvalue.String(value))
+		return element
+	}
+
+	shapes := map[string]*xmlquery.Node{}
+	var order []string
+	for child := shape.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != xmlquery.ElementNode {
+			continue
+		}
+		name := elementName(child)
+		if _, ok := shapes[name]; !ok {
+			shapes[name] = child
+			order = append(order, name)
+		}
+	}
+
+	var extra []string
+	for key := range record {
+		if _, ok := shapes[key]; !ok {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+
+	for _, key := range append(order, extra...) {
+		value, ok := record[key]
+		if !ok {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(key, xmlAttributePrefix):
+			xmlquery.AddAttr(element, strings.TrimPrefix(key, xmlAttributePrefix), jsonvalue.String(value))
+		case key == xmlTextField:
+			setText(element, jsonvalue.String(value))
+		default:
+			childShape := shapes[key]
+			if childShape == nil {
+				childShape = newElement(key)
+			}
+
+			values, ok := value.([]any)
+			if !ok {
+				values = []any{value}
+			}
+			for _, v := range values {
+				xmlquery.AddChild(element, recordElement(childShape, v))
+			}
+		}
+	}
+
+	return element
+}
+
+// findOrCreateElement returns the element selected by the XPath. A missing element
+// is created when the XPath ends with a plain element name whose parent exists.
+func findOrCreateElement(doc *xmlquery.Node, expr string) *xmlquery.Node {
+	if element, err := xmlquery.Query(doc, expr); err == nil && element != nil {
+		return element
+	}
+
+	parentExpr, name := ".", strings.TrimPrefix(expr, "./")
+	if i := strings.LastIndex(expr, "/"); i >= 0 {
+		parentExpr, name = expr[:i], expr[i+1:]
+	}
+	if parentExpr == "" || strings.ContainsAny(name, "[]()@*:/") || name == "" {
+		return nil
+	}
+
+	parent, err := xmlquery.Query(doc, parentExpr)
+	if err != nil || parent == nil {
+		return nil
+	}
+	// A relative XPath like next_token is created in the root element
+	if parent.Type == xmlquery.DocumentNode {
+		parent = rootElement(doc)
+		if parent == nil {
+			return nil
+		}
+	}
+
+	element := newElement(name)
+	xmlquery.AddChild(parent, element)
+
+	return element
+}
+
+// removeWhitespace removes the whitespace only text nodes of the tree
+func removeWhitespace(n *xmlquery.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == xmlquery.TextNode && strings.TrimSpace(child.Data) == "" {
+			xmlquery.RemoveFromTree(child)
+		} else {
+			removeWhitespace(child)
+		}
+		child = next
+	}
+}
+
+// rootElement returns the root element of the document
+func rootElement(doc *xmlquery.Node) *xmlquery.Node {
+	for child := doc.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == xmlquery.ElementNode {
+			return child
+		}
+	}
+	return nil
+}
+
+// insertAfter inserts the node into the parent after the anchor, or as the
+// first child when the anchor is nil
+func insertAfter(parent, anchor, n *xmlquery.Node) {
+	if anchor != nil {
+		xmlquery.AddImmediateSibling(anchor, n)
+		return
+	}
+
+	n.Parent = parent
+	n.PrevSibling = nil
+	n.NextSibling = parent.FirstChild
+	if parent.FirstChild != nil {
+		parent.FirstChild.PrevSibling = n
+	} else {
+		parent.LastChild = n
+	}
+	parent.FirstChild = n
+}
+
+// setText replaces the children of the element with the given text
+func setText(element *xmlquery.Node, text string) {
+	for child := element.FirstChild; child != nil; {
+		next := child.NextSibling
+		xmlquery.RemoveFromTree(child)
+		child = next
+	}
+	xmlquery.AddChild(element, &xmlquery.Node{Type: xmlquery.TextNode, Data: text})
+}
+
+func newElement(name string) *xmlquery.Node {
+	prefix := ""
+	if i := strings.Index(name, ":"); i >= 0 {
+		prefix, name = name[:i], name[i+1:]
+	}
+	return &xmlquery.Node{Type: xmlquery.ElementNode, Data: name, Prefix: prefix}
+}
+
+func elementName(element *xmlquery.Node) string {
+	if element.Prefix != "" {
+		return element.Prefix + ":" + element.Data
+	}
+	return element.Data
+}
+
+func attrName(attr xmlquery.Attr) string {
+	if attr.Name.Space != "" {
+		return attr.Name.Space + ":" + attr.Name.Local
+	}
+	return attr.Name.Local
+}
+
+// xmlValue converts the text to a number when it is written as one, so that the
+// records can be compared and numbered like the records of JSON responses
+func xmlValue(text string) any {
+	if n, err := strconv.ParseFloat(text, 64); err == nil && strconv.FormatFloat(n, 'f', -1, 64) == text {
+		return n
+	}
+	return text
+}
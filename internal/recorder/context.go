@@ -0,0 +1,15 @@
+package recorder
+
+import "context"
+
+type requestBodyKey struct{}
+
+// withRequestBody keeps the request body around until the upstream response is captured
+func withRequestBody(ctx context.Context, body []byte) context.Context {
+	return context.WithValue(ctx, requestBodyKey{}, body)
+}
+
+func requestBodyFrom(ctx context.Context) []byte {
+	body, _ := ctx.Value(requestBodyKey{}).([]byte)
+	return body
+}
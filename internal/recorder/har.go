@@ -0,0 +1,109 @@
+package recorder
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"mock-server/internal/config"
+	"mock-server/pkg/logger"
+)
+
+var (
+	errImportHAR = errors.New("failed to import har file")
+)
+
+// har is the part of a HAR file, e.g. exported by the browser developer
+// tools, that the import needs
+type har struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method   string `json:"method"`
+		URL      string `json:"url"`
+		PostData struct {
+			Text string `json:"text"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+// ImportHAR records the request/response pairs of the HAR file like the record
+// mode does. Only the requests whose URL starts with prefix, when it is set,
+// are imported. It returns the number of recorded endpoints.
+func ImportHAR(cfg *config.Record, harFile, prefix string) (int, error) {
+	var mockLogger = logger.GetLogger()
+
+	data, err := os.ReadFile(harFile)
+	if err != nil {
+		return 0, errors.Join(errImportHAR, err)
+	}
+
+	var archive har
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return 0, errors.Join(errImportHAR, err)
+	}
+
+	r, err := openRecording(cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, entry := range archive.Log.Entries {
+		if prefix != "" && !strings.HasPrefix(entry.Request.URL, prefix) {
+			continue
+		}
+
+		req, err := http.NewRequest(entry.Request.Method, entry.Request.URL, nil)
+		if err != nil {
+			mockLogger.Warn("skipping invalid har request", err)
+			continue
+		}
+
+		// Entries answered from the cache or blocked have no status
+		if entry.Response.Status == 0 {
+			continue
+		}
+		resp := &http.Response{StatusCode: entry.Response.Status, Header: make(http.Header)}
+		for _, h := range entry.Response.Headers {
+			resp.Header.Add(h.Name, h.Value)
+		}
+		if resp.Header.Get("Content-Type") == "" && entry.Response.Content.MimeType != "" {
+			resp.Header.Set("Content-Type", entry.Response.Content.MimeType)
+		}
+
+		body := []byte(entry.Response.Content.Text)
+		if entry.Response.Content.Encoding == "base64" {
+			if body, err = base64.StdEncoding.DecodeString(entry.Response.Content.Text); err != nil {
+				mockLogger.Warn("skipping invalid har response", err)
+				continue
+			}
+		}
+
+		if err := r.save(req, []byte(entry.Request.PostData.Text), resp, body); err != nil {
+			return imported, errors.Join(errImportHAR, err)
+		}
+		imported++
+	}
+
+	return imported, nil
+}
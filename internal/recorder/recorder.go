@@ -0,0 +1,331 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"mock-server/internal/config"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultOutputDir = "recordings"
+
+	// maxQueryNameLength is the length of the query in a response file name
+	maxQueryNameLength = 64
+)
+
+var (
+	errCreateRecorder = errors.New("failed to create recorder")
+	errSaveRecording  = errors.New("failed to save recording")
+)
+
+// Recorder proxies requests to the upstream and records every
+// request/response pair as an endpoint which can be replayed later
+type Recorder struct {
+	proxy      *httputil.ReverseProxy
+	outputDir  string
+	configFile string
+
+	mu        sync.Mutex
+	endpoints []config.Endpoint
+}
+
+// NewRecorder creates a recorder for the given record configuration. Endpoints
+// already present in the record config file are kept, so that several
+// recording sessions add up to one fixture set.
+func NewRecorder(cfg *config.Record) (*Recorder, error) {
+	var mockLogger = logger.GetLogger()
+
+	upstream, err := url.Parse(cfg.Upstream)
+	if err != nil {
+		mockLogger.Warn("invalid upstream url", err)
+		return nil, errors.Join(errCreateRecorder, err)
+	}
+
+	r, err := openRecording(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r.proxy = httputil.NewSingleHostReverseProxy(upstream)
+
+	director := r.proxy.Director
+	r.proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = upstream.Host
+		// Ask for an uncompressed body so that it can be stored as it is
+		req.Header.Del("Accept-Encoding")
+	}
+	r.proxy.ModifyResponse = r.capture
+
+	return r, nil
+}
+
+// openRecording creates a recorder adding to the endpoints of the record config file
+func openRecording(cfg *config.Record) (*Recorder, error) {
+	r := &Recorder{
+		outputDir:  defaultOutputDir,
+		configFile: cfg.ConfigFile,
+	}
+
+	if cfg.OutputDir != "" {
+		r.outputDir = cfg.OutputDir
+	}
+
+	recorded, err := loadRecordedConfig(r.configFile)
+	if err != nil {
+		logger.GetLogger().Warn("failed to load recorded config", err)
+		return nil, errors.Join(errCreateRecorder, err)
+	}
+	r.endpoints = recorded.Endpoints
+
+	return r, nil
+}
+
+// Record is the handler function which forwards the request to the upstream
+func (r *Recorder) Record(c *gin.Context) {
+	var requestBody []byte
+
+	if c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		requestBody = body
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	ctx := withRequestBody(c.Request.Context(), requestBody)
+	r.proxy.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
+
+// capture records the upstream response before it is sent to the client
+func (r *Recorder) capture(resp *http.Response) error {
+	var mockLogger = logger.GetLogger()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := r.save(resp.Request, requestBodyFrom(resp.Request.Context()), resp, body); err != nil {
+		// The client still gets the upstream response, only the recording is lost
+		mockLogger.ErrorW("failed to record response", err, map[string]any{
+			"method": resp.Request.Method,
+			"path":   resp.Request.URL.Path,
+		})
+	}
+
+	return nil
+}
+
+// save writes the response file and adds the endpoint to the record config file
+func (r *Recorder) save(req *http.Request, requestBody []byte, resp *http.Response, body []byte) error {
+	contentType := resp.Header.Get("Content-Type")
+
+	// A JSON request body is matched as a whole, in the canonical form the matcher compares
+	var (
+		requestValue  any
+		canonicalBody string
+	)
+	if len(requestBody) > 0 && json.Unmarshal(requestBody, &requestValue) == nil {
+		switch requestValue.(type) {
+		case map[string]any, []any:
+			data, _ := json.Marshal(requestValue)
+			canonicalBody = string(data)
+		}
+	}
+
+	// Bodies which are not JSON are saved as they are, with the extension of their content type
+	var responseObj any
+	query := req.URL.Query()
+	fileName := recordingFileName(req.Method, req.URL.Path, query.Encode(), canonicalBody)
+	isJSON := json.Unmarshal(body, &responseObj) == nil
+	if !isJSON {
+		fileName = strings.TrimSuffix(fileName, ".json") + rawFileExtension(contentType)
+	}
+
+	endpoint := config.Endpoint{
+		Path:                req.URL.Path,
+		Method:              req.Method,
+		Pagination:          config.Pagination{Type: "none"},
+		ResponseObjFilePath: filepath.Join(r.outputDir, fileName),
+		StatusCode:          resp.StatusCode,
+	}
+
+	// The query parameters select the recording among those of the path on replay
+	keys := slices.Sorted(maps.Keys(query))
+	for _, k := range keys {
+		endpoint.Matchers = append(endpoint.Matchers, config.Matcher{Location: "query", Key: k, Value: query.Get(k)})
+	}
+
+	// The request body tells the recordings of a search apart
+	if canonicalBody != "" {
+		endpoint.Matchers = append(endpoint.Matchers, config.Matcher{Location: "body", Key: "$", Value: canonicalBody})
+	}
+	if obj, ok := requestValue.(map[string]any); ok {
+		endpoint.RequestBody = obj
+	}
+
+	if contentType != "" {
+		endpoint.ResponseHeaders = map[string]any{"Content-Type": contentType}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var err error
+	responseFile := endpoint.ResponseObjFilePath
+	if !filepath.IsAbs(responseFile) {
+		responseFile = config.ResolveFilePath(responseFile)
+	}
+	if isJSON {
+		err = writeJSONFile(responseFile, responseObj)
+	} else {
+		err = writeFile(responseFile, body)
+	}
+	if err != nil {
+		return errors.Join(errSaveRecording, err)
+	}
+
+	replaced := false
+	for i, e := range r.endpoints {
+		if e.Method == endpoint.Method && e.Path == endpoint.Path && slices.Equal(e.Matchers, endpoint.Matchers) {
+			r.endpoints[i] = endpoint
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		r.endpoints = append(r.endpoints, endpoint)
+	}
+
+	if err := writeJSONFile(r.configFile, config.APIConfig{Endpoints: r.endpoints}); err != nil {
+		return errors.Join(errSaveRecording, err)
+	}
+
+	logger.GetLogger().InfoW("recorded endpoint", map[string]any{
+		"method": endpoint.Method,
+		"path":   endpoint.Path,
+		"file":   endpoint.ResponseObjFilePath,
+	})
+
+	return nil
+}
+
+// loadRecordedConfig loads the endpoints of an earlier recording session, if any
+func loadRecordedConfig(path string) (*config.APIConfig, error) {
+	recorded := &config.APIConfig{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return recorded, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, recorded); err != nil {
+		return nil, err
+	}
+
+	return recorded, nil
+}
+
+// recordingFileName builds the response file name from the method, path and
+// encoded query, e.g. GET /api/threats?page=2 -> get_api_threats_page_2.json.
+// A long query is shortened, with its hash keeping the names apart. The hash
+// of the request body is added when there is one.
+func recordingFileName(method, path, query, body string) string {
+	name := fileNamePart(strings.Trim(path, "/"))
+	if name == "" {
+		name = "root"
+	}
+
+	if query != "" {
+		part := fileNamePart(query)
+		if len(part) > maxQueryNameLength {
+			part = fmt.Sprintf("%s_%08x", part[:maxQueryNameLength], crc32.ChecksumIEEE([]byte(query)))
+		}
+		name += "_" + part
+	}
+
+	if body != "" {
+		name += fmt.Sprintf("_body_%08x", crc32.ChecksumIEEE([]byte(body)))
+	}
+
+	return strings.ToLower(method) + "_" + name + ".json"
+}
+
+// fileNamePart replaces the characters not allowed in a file name with underscores
+func fileNamePart(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// rawFileExtension returns the file extension of the content type, .bin when it is unknown
+func rawFileExtension(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch {
+	case mediaType == "text/plain":
+		return ".txt"
+	case mediaType == "text/csv":
+		return ".csv"
+	case mediaType == "text/html":
+		return ".html"
+	case strings.HasSuffix(mediaType, "xml"):
+		return ".xml"
+	}
+
+	if extensions, err := mime.ExtensionsByType(mediaType); err == nil && len(extensions) > 0 {
+		return extensions[0]
+	}
+
+	return ".bin"
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, data)
+}
+
+func writeFile(path string, data []byte) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,181 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mock-server/internal/config"
+	"mock-server/internal/matcher"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testRecorder returns a recorder of an upstream answering the request body it got
+func testRecorder(t *testing.T) *Recorder {
+	t.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"request":` + string(body) + `}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	dir := t.TempDir()
+	r, err := NewRecorder(&config.Record{
+		Upstream:   upstream.URL,
+		OutputDir:  filepath.Join(dir, "recordings"),
+		ConfigFile: filepath.Join(dir, "recorded.json"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// record sends the request through the recorder, the reverse proxy needs a real connection
+func record(t *testing.T, r *Recorder, method, target, body string) {
+	t.Helper()
+
+	engine := gin.New()
+	engine.NoRoute(r.Record)
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	req, err := http.NewRequest(method, server.URL+target, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s status = %d", method, target, resp.StatusCode)
+	}
+}
+
+// recorded returns the endpoints of the record config file
+func recorded(t *testing.T, r *Recorder) []config.Endpoint {
+	t.Helper()
+
+	cfg, err := loadRecordedConfig(r.configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.Endpoints
+}
+
+func TestRecordDedupe(t *testing.T) {
+	r := testRecorder(t)
+
+	record(t, r, http.MethodGet, "/threats?page=1", "")
+	record(t, r, http.MethodGet, "/threats?page=2", "")
+	record(t, r, http.MethodGet, "/threats?page=1", "")
+	record(t, r, http.MethodPost, "/search", `{"term":"a"}`)
+	record(t, r, http.MethodPost, "/search", `{"term":"b"}`)
+	record(t, r, http.MethodPost, "/search", `{ "term" : "a" }`)
+
+	endpoints := recorded(t, r)
+	if len(endpoints) != 4 {
+		t.Fatalf("recorded %d endpoints, want 4: %+v", len(endpoints), endpoints)
+	}
+
+	files := map[string]bool{}
+	for _, e := range endpoints {
+		files[e.ResponseObjFilePath] = true
+	}
+	if len(files) != len(endpoints) {
+		t.Errorf("endpoints share response files: %v", files)
+	}
+}
+
+func TestRecordBodyMatcher(t *testing.T) {
+	r := testRecorder(t)
+
+	record(t, r, http.MethodPost, "/search", `{"term":"a","filters":{"severity":1000000}}`)
+	record(t, r, http.MethodPost, "/search", `{"term":"b"}`)
+
+	endpoints := recorded(t, r)
+	if len(endpoints) != 2 {
+		t.Fatalf("recorded %d endpoints, want 2", len(endpoints))
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"same body", `{"term":"a","filters":{"severity":1000000}}`, "a"},
+		{"other key order and spacing", `{ "filters": {"severity": 1e6}, "term": "a" }`, "a"},
+		{"second body", `{"term":"b"}`, "b"},
+		{"unrecorded body", `{"term":"c"}`, ""},
+		{"no body", ``, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			for _, e := range endpoints {
+				matchers, err := matcher.NewMatchers(e.Matchers)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				c, _ := gin.CreateTestContext(httptest.NewRecorder())
+				c.Request = httptest.NewRequest(http.MethodPost, "/search", bytes.NewBufferString(tt.body))
+				if matcher.MatchAll(c, matchers) {
+					got, _ = e.RequestBody["term"].(string)
+				}
+			}
+			if got != tt.want {
+				t.Errorf("replayed the recording of %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The response file of the recording holds the response to its own body
+	data, err := os.ReadFile(endpoints[1].ResponseObjFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response map[string]map[string]any
+	if err := json.Unmarshal(data, &response); err != nil || response["request"]["term"] != "b" {
+		t.Errorf("response file = %s, want the response to term b", data)
+	}
+}
+
+func TestRecordingFileName(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		query  string
+		body   string
+		want   string
+	}{
+		{"path", "GET", "/api/threats", "", "", "get_api_threats.json"},
+		{"root", "GET", "/", "", "", "get_root.json"},
+		{"query", "GET", "/api/threats", "page=2", "", "get_api_threats_page_2.json"},
+		{"body", "POST", "/search", "", `{"term":"a"}`, "post_search_body_7662065b.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordingFileName(tt.method, tt.path, tt.query, tt.body); got != tt.want {
+				t.Errorf("recordingFileName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"mock-server/internal/config"
+	"mock-server/internal/pagination"
+	"mock-server/internal/store"
+	"mock-server/internal/template"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultIDParam = "id"
+	defaultIDField = "id"
+)
+
+var (
+	errCreateResource = errors.New("failed to create resource")
+)
+
+// Resource serves the CRUD operations of an in-memory collection seeded
+// from the response file of the endpoint
+type Resource struct {
+	path       string
+	idParam    string
+	collection *store.Collection
+	paginator  pagination.Paginator
+}
+
+// NewResource creates the resource of the given endpoint
+func NewResource(endpoint config.Endpoint) (*Resource, error) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("creating resource", map[string]any{"endpoint": endpoint.Path})
+
+	r := &Resource{
+		path:    strings.TrimSuffix(endpoint.Path, "/"),
+		idParam: defaultIDParam,
+	}
+
+	idField := defaultIDField
+	if idKey, ok := endpoint.Pagination.Options["idKey"].(string); ok {
+		idField = idKey
+	}
+	if endpoint.Lookup != nil {
+		if endpoint.Lookup.Param != "" {
+			r.idParam = endpoint.Lookup.Param
+		}
+		if endpoint.Lookup.Field != "" {
+			idField = endpoint.Lookup.Field
+		}
+	}
+
+	seed, err := pagination.LoadRecords(endpoint)
+	if err != nil {
+		mockLogger.Warn("failed to load resource records", err)
+		return nil, errors.Join(errCreateResource, err)
+	}
+	r.collection = store.NewCollection(idField, seed)
+
+	listEndpoint := endpoint
+	listEndpoint.Lookup = nil
+	r.paginator, err = pagination.CreateSourcePaginator(listEndpoint, r.collection)
+	if err != nil {
+		return nil, errors.Join(errCreateResource, err)
+	}
+
+	return r, nil
+}
+
+// Routes returns the routes of the operations of the resource
+func (r *Resource) Routes() gin.RoutesInfo {
+	item := fmt.Sprintf("%s/:%s", r.path, r.idParam)
+
+	return gin.RoutesInfo{
+		{Method: http.MethodGet, Path: r.path, HandlerFunc: r.paginator.Paginate},
+		{Method: http.MethodPost, Path: r.path, HandlerFunc: r.Create},
+		{Method: http.MethodGet, Path: item, HandlerFunc: r.Get},
+		{Method: http.MethodPut, Path: item, HandlerFunc: r.Replace},
+		{Method: http.MethodPatch, Path: item, HandlerFunc: r.Update},
+		{Method: http.MethodDelete, Path: item, HandlerFunc: r.Delete},
+	}
+}
+
+// Collection returns the collection of the resource
+func (r *Resource) Collection() *store.Collection {
+	return r.collection
+}
+
+// Create is the handler function creating a record
+func (r *Resource) Create(c *gin.Context) {
+	var record map[string]any
+	if err := c.ShouldBindJSON(&record); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request body must be a JSON object"})
+		return
+	}
+
+	created, err := r.collection.Create(record)
+	switch {
+	case errors.Is(err, store.ErrDuplicateID):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template.Render(created, c))
+}
+
+// Get is the handler function reading a record
+func (r *Resource) Get(c *gin.Context) {
+	record, found := r.collection.Get(c.Param(r.idParam))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template.Render(record, c))
+}
+
+// Replace is the handler function replacing a record
+func (r *Resource) Replace(c *gin.Context) {
+	var record map[string]any
+	if err := c.ShouldBindJSON(&record); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request body must be a JSON object"})
+		return
+	}
+
+	record, found := r.collection.Replace(c.Param(r.idParam), record)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template.Render(record, c))
+}
+
+// Update is the handler function updating the given fields of a record
+func (r *Resource) Update(c *gin.Context) {
+	var fields map[string]any
+	if err := c.ShouldBindJSON(&fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request body must be a JSON object"})
+		return
+	}
+
+	record, found := r.collection.Update(c.Param(r.idParam), fields)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template.Render(record, c))
+}
+
+// Delete is the handler function removing a record
+func (r *Resource) Delete(c *gin.Context) {
+	if !r.collection.Delete(c.Param(r.idParam)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
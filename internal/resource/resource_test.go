@@ -0,0 +1,126 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mock-server/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testEngine returns an engine serving a ticket resource seeded with the given records
+func testEngine(t *testing.T, records []any, pagination config.Pagination) *gin.Engine {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"tickets": records})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "tickets.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewResource(config.Endpoint{
+		Type:                "resource",
+		Path:                "/tickets",
+		Pagination:          pagination,
+		ResponseObjFilePath: path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := gin.New()
+	for _, route := range r.Routes() {
+		engine.Handle(route.Method, route.Path, route.HandlerFunc)
+	}
+	return engine
+}
+
+// send sends the request with the JSON body and returns the status and the decoded body
+func send(engine *gin.Engine, method, target, body string) (int, any) {
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(method, target, bytes.NewBufferString(body)))
+
+	var resp any
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestListPagesOfTheCollection(t *testing.T) {
+	engine := testEngine(t, []any{map[string]any{"id": float64(1)}}, config.Pagination{
+		Type:    "page",
+		Options: map[string]any{"pageSize": float64(10)},
+	})
+	for i := 2; i <= 30; i++ {
+		if status, resp := send(engine, http.MethodPost, "/tickets", `{}`); status != http.StatusCreated {
+			t.Fatalf("create status = %d: %v", status, resp)
+		}
+	}
+
+	tests := []struct {
+		target string
+		status int
+		first  float64
+		count  int
+	}{
+		{"/tickets?page=1", http.StatusOK, 1, 10},
+		{"/tickets?page=3", http.StatusOK, 21, 10},
+		{"/tickets?page=4", http.StatusNotFound, 0, 0},
+	}
+
+	for _, tt := range tests {
+		status, resp := send(engine, http.MethodGet, tt.target, "")
+		if status != tt.status {
+			t.Fatalf("GET %s status = %d, want %d: %v", tt.target, status, tt.status, resp)
+		}
+		if status != http.StatusOK {
+			continue
+		}
+		tickets, _ := resp.(map[string]any)["tickets"].([]any)
+		if len(tickets) != tt.count || tickets[0].(map[string]any)["id"] != tt.first {
+			t.Errorf("GET %s = %v, want %d tickets from id %v", tt.target, tickets, tt.count, tt.first)
+		}
+	}
+}
+
+func TestCreateStatus(t *testing.T) {
+	engine := testEngine(t, []any{map[string]any{"id": float64(1)}}, config.Pagination{})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"given id", `{"id":5}`, http.StatusCreated},
+		{"existing id", `{"id":5}`, http.StatusConflict},
+		{"existing seed id", `{"id":"1"}`, http.StatusConflict},
+		{"null id", `{"id":null}`, http.StatusBadRequest},
+		{"object id", `{"id":{"n":1}}`, http.StatusBadRequest},
+		{"not an object", `[]`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		if status, resp := send(engine, http.MethodPost, "/tickets", tt.body); status != tt.status {
+			t.Errorf("%s: status = %d, want %d: %v", tt.name, status, tt.status, resp)
+		}
+	}
+
+	if status, _ := send(engine, http.MethodDelete, "/tickets/5", ""); status != http.StatusNoContent {
+		t.Fatalf("delete status = %d, want %d", status, http.StatusNoContent)
+	}
+	if status, _ := send(engine, http.MethodGet, "/tickets/5", ""); status != http.StatusNotFound {
+		t.Errorf("get of the deleted record status = %d, want %d", status, http.StatusNotFound)
+	}
+}
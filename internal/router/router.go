@@ -2,45 +2,219 @@ package router
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 
 	"mock-server/internal/config"
+	"mock-server/internal/graphql"
+	"mock-server/internal/job"
+	"mock-server/internal/matcher"
 	"mock-server/internal/pagination"
+	"mock-server/internal/resource"
+	"mock-server/internal/state"
+	"mock-server/internal/wsmock"
 
 	"github.com/gin-gonic/gin"
 )
 
+type endpointType string
+
+const (
+	resourceEndpoint  endpointType = "resource"
+	graphqlEndpoint   endpointType = "graphql"
+	websocketEndpoint endpointType = "websocket"
+	jobEndpoint       endpointType = "job"
+)
+
 var (
-	errSetupRoutes = errors.New("failed to setup routes")
+	errSetupRoutes   = errors.New("failed to setup routes")
+	errRouteConflict = errors.New("route conflicts with a registered route")
 )
 
+// route groups the endpoints sharing the same method and path
+type route struct {
+	method string
+	path   string
+	stubs  []stub
+}
+
+// stub is a single endpoint of a route, selected by its matchers
+type stub struct {
+	matchers []matcher.Matcher
+	priority int
+	handler  gin.HandlerFunc
+}
+
 // SetupRoutes configures all routes based on the API config
-func SetupRoutes(engine *gin.Engine, cfg *config.APIConfig) error {
+func SetupRoutes(engine *gin.Engine, cfg *config.APIConfig, registry *state.Registry) error {
+	var routes []*route
 
-	// Register all endpoints from config
+	// Group the endpoints by method and path, keeping the config order
 	for _, endpoint := range cfg.Endpoints {
 
-		// Create the paginator for the endpoint
-		paginator, err := pagination.CreatePaginator(endpoint)
+		// Resources register the routes of all their operations
+		if endpointType(endpoint.Type) == resourceEndpoint {
+			res, err := resource.NewResource(endpoint)
+			if err != nil {
+				return errors.Join(errSetupRoutes, err)
+			}
+			if err := RegisterRoutes(engine, res.Routes()); err != nil {
+				return errors.Join(errSetupRoutes, err)
+			}
+			registry.Register("resource:"+endpoint.Path, res.Collection())
+			continue
+		}
+
+		// GraphQL endpoints serve their schema on GET and POST
+		if endpointType(endpoint.Type) == graphqlEndpoint {
+			srv, err := graphql.NewServer(endpoint)
+			if err != nil {
+				return errors.Join(errSetupRoutes, err)
+			}
+			if err := RegisterRoutes(engine, srv.Routes()); err != nil {
+				return errors.Join(errSetupRoutes, err)
+			}
+			continue
+		}
+
+		// Job endpoints register the routes of the job status and result
+		if endpointType(endpoint.Type) == jobEndpoint {
+			queue, err := job.NewQueue(endpoint)
+			if err != nil {
+				return errors.Join(errSetupRoutes, err)
+			}
+			if err := RegisterRoutes(engine, queue.Routes()); err != nil {
+				return errors.Join(errSetupRoutes, err)
+			}
+			registry.Register("job:"+endpoint.Path, queue)
+			continue
+		}
+
+		var handler gin.HandlerFunc
+		var stateful state.Stateful
+
+		// Websocket endpoints upgrade GET requests, they share the routes of the other endpoints
+		if endpointType(endpoint.Type) == websocketEndpoint {
+			srv, err := wsmock.NewServer(endpoint)
+			if err != nil {
+				return errors.Join(errSetupRoutes, err)
+			}
+			endpoint.Method = http.MethodGet
+			handler = srv.Serve
+		} else {
+			// Create the paginator for the endpoint
+			paginator, err := pagination.CreatePaginator(endpoint)
+			if err != nil {
+				return errors.Join(errSetupRoutes, err)
+			}
+			handler = paginator.Paginate
+			stateful, _ = pagination.Stateful(paginator)
+		}
+
+		matchers, err := matcher.NewMatchers(endpoint.Matchers)
 		if err != nil {
 			return errors.Join(errSetupRoutes, err)
 		}
 
-		// Register the handler with the appropriate HTTP method
-		switch endpoint.Method {
-		case http.MethodGet:
-			engine.GET(endpoint.Path, paginator.Paginate)
-		case http.MethodPost:
-			engine.POST(endpoint.Path, paginator.Paginate)
-		case http.MethodPut:
-			engine.PUT(endpoint.Path, paginator.Paginate)
-		case http.MethodDelete:
-			engine.DELETE(endpoint.Path, paginator.Paginate)
-		case http.MethodPatch:
-			engine.PATCH(endpoint.Path, paginator.Paginate)
-		default:
-			engine.Any(endpoint.Path, paginator.Paginate)
+		var r *route
+		for _, existing := range routes {
+			if existing.method == endpoint.Method && existing.path == endpoint.Path {
+				r = existing
+				break
+			}
+		}
+		if r == nil {
+			r = &route{method: endpoint.Method, path: endpoint.Path}
+			routes = append(routes, r)
 		}
+
+		// Timelines and mutable datasets are persisted like the resources, the
+		// endpoints sharing a route are told apart by their position
+		if stateful != nil {
+			name := "dataset:" + endpoint.Method + " " + endpoint.Path
+			if len(r.stubs) > 0 {
+				name += "#" + strconv.Itoa(len(r.stubs)+1)
+			}
+			registry.Register(name, stateful)
+		}
+
+		r.stubs = append(r.stubs, stub{
+			matchers: matchers,
+			priority: endpoint.Priority,
+			handler:  handler,
+		})
+	}
+
+	// Register all routes
+	for _, r := range routes {
+		if err := registerRoute(engine, r.method, r.path, r.handler()); err != nil {
+			return errors.Join(errSetupRoutes, err)
+		}
+	}
+	return nil
+}
+
+// RegisterRoutes registers the routes of an endpoint serving several operations,
+// e.g. a resource, and returns the first route conflicting with a registered one
+func RegisterRoutes(engine *gin.Engine, routes gin.RoutesInfo) error {
+	for _, r := range routes {
+		if err := registerRoute(engine, r.Method, r.Path, r.HandlerFunc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handler returns the handler dispatching the request to the matching stub
+func (r *route) handler() gin.HandlerFunc {
+	if len(r.stubs) == 1 && len(r.stubs[0].matchers) == 0 {
+		return r.stubs[0].handler
+	}
+
+	// Higher priority first, the stubs without matchers are the fallback
+	sort.SliceStable(r.stubs, func(i, j int) bool {
+		if r.stubs[i].priority != r.stubs[j].priority {
+			return r.stubs[i].priority > r.stubs[j].priority
+		}
+		return len(r.stubs[i].matchers) > 0 && len(r.stubs[j].matchers) == 0
+	})
+
+	return func(c *gin.Context) {
+		for _, s := range r.stubs {
+			if matcher.MatchAll(c, s.matchers) {
+				s.handler(c)
+				return
+			}
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "no matching stub found"})
+	}
+}
+
+// registerRoute registers the handler with the appropriate HTTP method. gin panics
+// when the route conflicts with a registered one, e.g. a static stub next to the
+// :id route of a resource, the conflict is returned as an error instead.
+func registerRoute(engine *gin.Engine, method, path string, handler gin.HandlerFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %s %s: %v", errRouteConflict, method, path, r)
+		}
+	}()
+
+	switch method {
+	case http.MethodGet:
+		engine.GET(path, handler)
+	case http.MethodPost:
+		engine.POST(path, handler)
+	case http.MethodPut:
+		engine.PUT(path, handler)
+	case http.MethodDelete:
+		engine.DELETE(path, handler)
+	case http.MethodPatch:
+		engine.PATCH(path, handler)
+	default:
+		engine.Handle(method, path, handler)
 	}
 	return nil
 }
@@ -0,0 +1,120 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mock-server/internal/config"
+	"mock-server/internal/state"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// writeFile writes the file and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRouteConflicts(t *testing.T) {
+	file := writeFile(t, "response.json", `{"items":[{"id":1}]}`)
+	items := config.Endpoint{Type: "resource", Path: "/items", ResponseObjFilePath: file}
+	exports := config.Endpoint{Type: "job", Path: "/exports", Method: "POST", Job: &config.Job{}, ResponseObjFilePath: file}
+	graph := config.Endpoint{Type: "graphql", Path: "/graphql", GraphQL: &config.GraphQL{SchemaFile: writeFile(t, "schema.graphql", "type Query { ping: String }")}}
+
+	tests := []struct {
+		name      string
+		endpoints []config.Endpoint
+		conflict  bool
+	}{
+		{"resource alone", []config.Endpoint{items}, false},
+		{"static stub next to the item route", []config.Endpoint{items, {Path: "/items/search", Method: "GET", ResponseObjFilePath: file}}, false},
+		{"stub on the list route", []config.Endpoint{items, {Path: "/items", Method: "GET", ResponseObjFilePath: file}}, true},
+		{"stub with another id parameter", []config.Endpoint{items, {Path: "/items/:itemId", Method: "GET", ResponseObjFilePath: file}}, true},
+		{"two resources on a path", []config.Endpoint{items, items}, true},
+		{"job alone", []config.Endpoint{exports}, false},
+		{"stub on the job status route", []config.Endpoint{exports, {Path: "/exports/:id", Method: "GET", ResponseObjFilePath: file}}, true},
+		{"job status on a resource item route", []config.Endpoint{items, {Type: "job", Path: "/exports", Method: "POST", Job: &config.Job{StatusPath: "/items/:jobId"}, ResponseObjFilePath: file}}, true},
+		{"graphql alone", []config.Endpoint{graph}, false},
+		{"stub on the graphql route", []config.Endpoint{graph, {Path: "/graphql", Method: "POST", ResponseObjFilePath: file}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetupRoutes(gin.New(), &config.APIConfig{Endpoints: tt.endpoints}, state.NewRegistry(""))
+			if got := errors.Is(err, errRouteConflict); got != tt.conflict {
+				t.Errorf("SetupRoutes() error = %v, want a conflict %v", err, tt.conflict)
+			}
+		})
+	}
+}
+
+func TestStubPriority(t *testing.T) {
+	stub := func(name string, priority int, matchers ...config.Matcher) config.Endpoint {
+		return config.Endpoint{
+			Path:                "/alerts",
+			Method:              "GET",
+			ResponseObjFilePath: writeFile(t, name+".json", `{"stub":"`+name+`"}`),
+			Matchers:            matchers,
+			Priority:            priority,
+		}
+	}
+	open := config.Matcher{Location: "query", Key: "status", Value: "open"}
+	tenant := config.Matcher{Location: "header", Key: "X-Tenant", Operator: "exists"}
+
+	tests := []struct {
+		name      string
+		endpoints []config.Endpoint
+		target    string
+		header    map[string]string
+		status    int
+		want      string
+	}{
+		{"matcher stub before the fallback", []config.Endpoint{stub("fallback", 0), stub("open", 0, open)}, "/alerts?status=open", nil, http.StatusOK, "open"},
+		{"fallback", []config.Endpoint{stub("fallback", 0), stub("open", 0, open)}, "/alerts?status=closed", nil, http.StatusOK, "fallback"},
+		{"higher priority first", []config.Endpoint{stub("open", 0, open), stub("tenant", 10, open, tenant)}, "/alerts?status=open", map[string]string{"X-Tenant": "acme"}, http.StatusOK, "tenant"},
+		{"higher priority not matched", []config.Endpoint{stub("open", 0, open), stub("tenant", 10, open, tenant)}, "/alerts?status=open", nil, http.StatusOK, "open"},
+		{"configuration order on equal priority", []config.Endpoint{stub("first", 0, open), stub("second", 0, open)}, "/alerts?status=open", nil, http.StatusOK, "first"},
+		{"fallback with a higher priority", []config.Endpoint{stub("open", 0, open), stub("fallback", 5)}, "/alerts?status=open", nil, http.StatusOK, "fallback"},
+		{"no stub matched", []config.Endpoint{stub("open", 0, open), stub("tenant", 10, tenant)}, "/alerts", nil, http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			if err := SetupRoutes(engine, &config.APIConfig{Endpoints: tt.endpoints}, state.NewRegistry("")); err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			var body map[string]any
+			_ = json.Unmarshal(w.Body.Bytes(), &body)
+			if got, _ := body["stub"].(string); got != tt.want {
+				t.Errorf("served stub %q, want %q: %s", got, tt.want, w.Body)
+			}
+		})
+	}
+}
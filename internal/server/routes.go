@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"sort"
+
+	"mock-server/internal/config"
+	"mock-server/internal/grpcmock"
+
+	"github.com/gin-gonic/gin"
+)
+
+const grpcMethod = "GRPC"
+
+// Route is a route the mock server serves, on all the hosts of its port when
+// Hosts is empty
+type Route struct {
+	Port   string
+	Hosts  []string
+	Method string
+	Path   string
+}
+
+// Routes builds the endpoints of the config like the server does, without
+// listening, and returns their routes. The gRPC methods have the GRPC method.
+func Routes(cfg *config.APIConfig) ([]Route, error) {
+	m, err := setup(cfg, os.Getenv("PORT"))
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make([]string, 0, len(m.listeners))
+	for port := range m.listeners {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+
+	var routes []Route
+	for _, port := range ports {
+		l := m.listeners[port]
+		if engine, ok := l.fallback.(*gin.Engine); ok {
+			routes = append(routes, engineRoutes(port, nil, engine)...)
+		}
+
+		// The hosts of a virtual host share its engine
+		var handlers []http.Handler
+		hosts := make(map[http.Handler][]string)
+		for host, handler := range l.hosts {
+			if _, found := hosts[handler]; !found {
+				handlers = append(handlers, handler)
+			}
+			hosts[handler] = append(hosts[handler], host)
+		}
+		for _, handler := range handlers {
+			sort.Strings(hosts[handler])
+		}
+		sort.Slice(handlers, func(i, j int) bool {
+			return hosts[handlers[i]][0] < hosts[handlers[j]][0]
+		})
+		for _, handler := range handlers {
+			if engine, ok := handler.(*gin.Engine); ok {
+				routes = append(routes, engineRoutes(port, hosts[handler], engine)...)
+			}
+		}
+	}
+
+	if cfg.GRPC != nil {
+		// Loading the descriptors checks the configured methods
+		if _, err := grpcmock.NewServer(cfg.GRPC, nil); err != nil {
+			return nil, err
+		}
+
+		methods := make([]string, 0, len(cfg.GRPC.Methods))
+		for name := range cfg.GRPC.Methods {
+			methods = append(methods, name)
+		}
+		sort.Strings(methods)
+		for _, name := range methods {
+			routes = append(routes, Route{Port: cfg.GRPC.Port, Method: grpcMethod, Path: name})
+		}
+	}
+
+	return routes, nil
+}
+
+// engineRoutes returns the routes of the engine sorted by path and method
+func engineRoutes(port string, hosts []string, engine *gin.Engine) []Route {
+	info := engine.Routes()
+	sort.Slice(info, func(i, j int) bool {
+		if info[i].Path != info[j].Path {
+			return info[i].Path < info[j].Path
+		}
+		return info[i].Method < info[j].Method
+	})
+
+	routes := make([]Route, 0, len(info))
+	for _, r := range info {
+		routes = append(routes, Route{Port: port, Hosts: hosts, Method: r.Method, Path: r.Path})
+	}
+	return routes
+}
@@ -2,52 +2,114 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"time"
 
+	"mock-server/internal/admin"
 	"mock-server/internal/config"
+	"mock-server/internal/grpcmock"
 	"mock-server/internal/middleware"
+	"mock-server/internal/recorder"
 	"mock-server/internal/router"
+	"mock-server/internal/state"
+	"mock-server/internal/tlsconfig"
+	"mock-server/internal/webhook"
 	"mock-server/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	defaultReadTimeout  = 5 * time.Second
+	defaultWriteTimeout = 10 * time.Second
+	defaultIdleTimeout  = 15 * time.Second
+)
+
+var (
+	errSetupServer = errors.New("failed to setup server")
+)
+
 func CreateServer(ctx context.Context, cfg *config.APIConfig) error {
 	var (
 		mockLogger = logger.GetLogger()
-		port      = os.Getenv("PORT")
+		port       = os.Getenv("PORT")
+		host       = os.Getenv("HOST")
 	)
 
-	engine := gin.New()
-	engine.Use(gin.Recovery())
-	engine.Use(middleware.LoggerMiddleware())
-
-	// Setup routers
-	err := router.SetupRoutes(engine, cfg)
+	m, err := setup(cfg, port)
 	if err != nil {
 		return err
 	}
+	registry := m.registry
 
-	// Create HTTP server with timeouts
-	httpServer := &http.Server{
-		Addr:         ":" + port,
-		Handler:      engine,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  15 * time.Second,
+	// Restore the state of the previous run
+	if err := registry.Load(); err != nil {
+		return err
+	}
+
+	// Serve HTTPS when TLS is configured
+	var tlsConfig *tls.Config
+	if cfg.TLS != nil {
+		tlsConfig, err = tlsconfig.Load(cfg.TLS, virtualHostNames(cfg.VirtualHosts))
+		if err != nil {
+			return err
+		}
 	}
 
-	serverErr := make(chan error, 1)
+	// Create an HTTP server per listener
+	httpServers := make([]*http.Server, 0, len(m.listeners))
+	for _, l := range m.listeners {
+		httpServers = append(httpServers, newHTTPServer(cfg, net.JoinHostPort(host, l.port), l.handler(), tlsConfig))
+	}
+
+	serverErr := make(chan error, len(httpServers)+1)
 
-	go func() {
-		mockLogger.InfoW("Server starting", map[string]any{"port": port})
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			serverErr <- fmt.Errorf("server failed: %w", err)
+	// Serve the gRPC services next to the HTTP server
+	var grpcServer *grpcmock.Server
+	if cfg.GRPC != nil {
+		grpcServer, err = grpcmock.NewServer(cfg.GRPC, tlsConfig)
+		if err != nil {
+			return err
 		}
-	}()
+
+		go func() {
+			mockLogger.InfoW("gRPC server starting", map[string]any{"port": cfg.GRPC.Port})
+			if err := grpcServer.Serve(); err != nil {
+				serverErr <- fmt.Errorf("grpc server failed: %w", err)
+			}
+		}()
+	}
+
+	if m.emitter != nil {
+		m.emitter.Run(ctx)
+	}
+
+	if cfg.Persistence != nil && cfg.Persistence.Interval != "" {
+		interval, _ := time.ParseDuration(cfg.Persistence.Interval)
+		go persistState(ctx, registry, interval)
+	}
+
+	for _, httpServer := range httpServers {
+		go func() {
+			mockLogger.InfoW("Server starting", map[string]any{"addr": httpServer.Addr, "tls": tlsConfig != nil, "protocols": httpServer.Protocols.String()})
+
+			var err error
+			if tlsConfig != nil {
+				// The certificates are in the TLS config
+				err = httpServer.ListenAndServeTLS("", "")
+			} else {
+				err = httpServer.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				serverErr <- fmt.Errorf("server failed: %w", err)
+			}
+		}()
+	}
 
 	// Wait for shutdown signal or server error
 	select {
@@ -59,10 +121,139 @@ func CreateServer(ctx context.Context, cfg *config.APIConfig) error {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		if err := httpServer.Shutdown(shutdownCtx); err != nil {
-			return fmt.Errorf("graceful shutdown failed: %w", err)
+		for _, httpServer := range httpServers {
+			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+				return fmt.Errorf("graceful shutdown failed: %w", err)
+			}
+		}
+		if grpcServer != nil {
+			grpcServer.Stop(shutdownCtx)
+		}
+
+		if registry.Persistent() {
+			if _, err := registry.Save(); err != nil {
+				return err
+			}
+			mockLogger.Info("Mock state saved")
 		}
 		mockLogger.Info("Server stopped")
 		return nil
 	}
 }
+
+// mock is the HTTP side of the mock server built from the config
+type mock struct {
+	engine    *gin.Engine
+	registry  *state.Registry
+	emitter   *webhook.Emitter
+	listeners map[string]*listener
+}
+
+// setup builds the engines of the main endpoints and of the virtual hosts
+func setup(cfg *config.APIConfig, port string) (*mock, error) {
+	var mockLogger = logger.GetLogger()
+
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	engine.Use(middleware.LoggerMiddleware())
+
+	registry := state.NewRegistry("")
+	if cfg.Persistence != nil {
+		registry = state.NewRegistry(cfg.Persistence.File)
+	}
+
+	// Setup routers
+	err := router.SetupRoutes(engine, cfg, registry)
+	if err != nil {
+		return nil, err
+	}
+	admin.RegisterRoutes(engine, registry)
+
+	// Serve the webhook subscriptions and deliver their events
+	var emitter *webhook.Emitter
+	if cfg.Webhooks != nil {
+		emitter, err = webhook.NewEmitter(cfg.Webhooks)
+		if err != nil {
+			return nil, err
+		}
+		if err := router.RegisterRoutes(engine, emitter.Routes()); err != nil {
+			return nil, errors.Join(errSetupServer, err)
+		}
+		registry.Register("webhooks", emitter.Subscriptions())
+	}
+
+	// Serve the virtual hosts on their ports, or on the main port by their Host header
+	listeners, err := setupListeners(engine, port, cfg.VirtualHosts, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	// Forward the requests without a configured endpoint to the upstream
+	if cfg.Record != nil {
+		rec, err := recorder.NewRecorder(cfg.Record)
+		if err != nil {
+			return nil, err
+		}
+		engine.NoRoute(rec.Record)
+		mockLogger.InfoW("record mode enabled", map[string]any{"upstream": cfg.Record.Upstream})
+	}
+
+	return &mock{engine: engine, registry: registry, emitter: emitter, listeners: listeners}, nil
+}
+
+// newHTTPServer creates the HTTP server of a listener with the configured timeouts
+func newHTTPServer(cfg *config.APIConfig, addr string, handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	timeouts := cfg.Timeouts
+	if timeouts == nil {
+		timeouts = &config.Timeouts{}
+	}
+
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  duration(timeouts.Read, defaultReadTimeout),
+		WriteTimeout: duration(timeouts.Write, defaultWriteTimeout),
+		IdleTimeout:  duration(timeouts.Idle, defaultIdleTimeout),
+		TLSConfig:    tlsConfig,
+		Protocols:    protocols(cfg.Protocols),
+	}
+}
+
+// duration parses the configured duration, validated with the config, or
+// returns the default when it is not set
+func duration(value string, defaultValue time.Duration) time.Duration {
+	if value == "" {
+		return defaultValue
+	}
+	d, _ := time.ParseDuration(value)
+	return d
+}
+
+// protocols returns the HTTP versions the server speaks, HTTP/1.1 and HTTP/2
+// over TLS by default
+func protocols(cfg *config.Protocols) *http.Protocols {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetHTTP2(cfg == nil || !cfg.HTTP1Only)
+	p.SetUnencryptedHTTP2(cfg != nil && cfg.H2C)
+	return p
+}
+
+// persistState saves the mock state every interval until the context is done
+func persistState(ctx context.Context, registry *state.Registry, interval time.Duration) {
+	var mockLogger = logger.GetLogger()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := registry.Save(); err != nil {
+				mockLogger.Error("failed to save mock state", err)
+			}
+		}
+	}
+}
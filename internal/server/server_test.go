@@ -0,0 +1,174 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mock-server/internal/config"
+	"mock-server/internal/tlsconfig"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// writeFile writes the file and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWebhookRouteConflicts(t *testing.T) {
+	file := writeFile(t, "response.json", `{"items":[{"id":1}]}`)
+
+	tests := []struct {
+		name     string
+		path     string
+		endpoint config.Endpoint
+		wantErr  bool
+	}{
+		{"default path", "", config.Endpoint{Path: "/items", Method: "GET", ResponseObjFilePath: file}, false},
+		{"path of a stub", "/items", config.Endpoint{Path: "/items", Method: "GET", ResponseObjFilePath: file}, true},
+		{"item route next to a stub", "/hooks", config.Endpoint{Path: "/hooks/:hookId", Method: "GET", ResponseObjFilePath: file}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.APIConfig{
+				Endpoints: []config.Endpoint{tt.endpoint},
+				Webhooks:  &config.Webhooks{SubscriptionPath: tt.path, Events: []config.WebhookEvent{{Name: "item.created"}}},
+			}
+			if _, err := setup(cfg, "0"); (err != nil) != tt.wantErr {
+				t.Errorf("setup() error = %v, want an error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPServerTimeouts(t *testing.T) {
+	tests := []struct {
+		name              string
+		timeouts          *config.Timeouts
+		read, write, idle time.Duration
+	}{
+		{"defaults", nil, 5 * time.Second, 10 * time.Second, 15 * time.Second},
+		{"configured", &config.Timeouts{Read: "1s", Write: "2m"}, time.Second, 2 * time.Minute, 15 * time.Second},
+		{"turned off", &config.Timeouts{Write: "0s"}, 5 * time.Second, 0, 15 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newHTTPServer(&config.APIConfig{Timeouts: tt.timeouts}, ":0", http.NotFoundHandler(), nil)
+			if s.ReadTimeout != tt.read || s.WriteTimeout != tt.write || s.IdleTimeout != tt.idle {
+				t.Errorf("timeouts = %v, %v, %v, want %v, %v, %v", s.ReadTimeout, s.WriteTimeout, s.IdleTimeout, tt.read, tt.write, tt.idle)
+			}
+		})
+	}
+}
+
+// startHTTPServer serves the HTTP server of the config on a random port and
+// returns its URL
+func startHTTPServer(t *testing.T, cfg *config.APIConfig, tlsConfig *tls.Config) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The handler answers the protocol of the request
+	s := newHTTPServer(cfg, ln.Addr().String(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	}), tlsConfig)
+	t.Cleanup(func() { s.Close() })
+
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+		go s.ServeTLS(ln, "", "")
+	} else {
+		go s.Serve(ln)
+	}
+	return scheme + "://" + ln.Addr().String()
+}
+
+// protocolOf sends a request with the client and returns the protocol the server answered
+func protocolOf(t *testing.T, client *http.Client, url string) (string, error) {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestProtocols(t *testing.T) {
+	tlsConfig, err := tlsconfig.Load(&config.TLS{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The client negotiates HTTP/2 over TLS when the server offers it
+	tlsClient := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+
+	// The client speaks HTTP/2 over cleartext with prior knowledge, like curl --http2-prior-knowledge
+	priorKnowledge := new(http.Protocols)
+	priorKnowledge.SetUnencryptedHTTP2(true)
+	h2cClient := &http.Client{Transport: &http.Transport{Protocols: priorKnowledge}}
+
+	tests := []struct {
+		name      string
+		protocols *config.Protocols
+		tls       *tls.Config
+		client    *http.Client
+		want      string
+	}{
+		{"http2 over tls by default", nil, tlsConfig, tlsClient, "HTTP/2.0"},
+		{"http1 only over tls", &config.Protocols{HTTP1Only: true}, tlsConfig, tlsClient, "HTTP/1.1"},
+		{"http1 over cleartext by default", nil, nil, http.DefaultClient, "HTTP/1.1"},
+		{"h2c with prior knowledge", &config.Protocols{H2C: true}, nil, h2cClient, "HTTP/2.0"},
+		{"h2c refused without the option", nil, nil, h2cClient, ""},
+		{"http1 next to h2c", &config.Protocols{H2C: true}, nil, http.DefaultClient, "HTTP/1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := startHTTPServer(t, &config.APIConfig{Protocols: tt.protocols}, tt.tls)
+
+			got, err := protocolOf(t, tt.client, url)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("request answered over %s, want it refused", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("protocol = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
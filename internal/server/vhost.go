@@ -0,0 +1,156 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"mock-server/internal/config"
+	"mock-server/internal/middleware"
+	"mock-server/internal/router"
+	"mock-server/internal/state"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listener serves the requests of a port, dispatching them to the virtual hosts
+// by their Host header
+type listener struct {
+	port     string
+	hosts    map[string]http.Handler
+	fallback http.Handler
+}
+
+// ServeHTTP serves the request with the handler of its host. The hosts like
+// *.vendor.local match all the subdomains of vendor.local.
+func (l *listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	if handler, found := l.hosts[host]; found {
+		handler.ServeHTTP(w, r)
+		return
+	}
+	for i := strings.Index(host, "."); i >= 0; i = strings.Index(host, ".") {
+		host = host[i+1:]
+		if handler, found := l.hosts["*."+host]; found {
+			handler.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	if l.fallback == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"unknown host"}`)
+		return
+	}
+	l.fallback.ServeHTTP(w, r)
+}
+
+// handler returns the handler of the listener, without host dispatching when
+// there is a single handler
+func (l *listener) handler() http.Handler {
+	if len(l.hosts) == 0 && l.fallback != nil {
+		return l.fallback
+	}
+	return l
+}
+
+// setupListeners returns the listeners by port, the main engine answers on the
+// main port and the virtual hosts on their ports or hosts
+func setupListeners(engine *gin.Engine, port string, hosts []config.VirtualHost, registry *state.Registry) (map[string]*listener, error) {
+	listeners := map[string]*listener{
+		port: {port: port, hosts: make(map[string]http.Handler), fallback: engine},
+	}
+
+	for _, vhost := range hosts {
+		handler, err := newVirtualHost(vhost, registry)
+		if err != nil {
+			return nil, err
+		}
+
+		p := vhost.Port
+		if p == "" {
+			p = port
+		}
+		l, found := listeners[p]
+		if !found {
+			l = &listener{port: p, hosts: make(map[string]http.Handler)}
+			listeners[p] = l
+		}
+
+		if len(vhost.Hosts) == 0 {
+			if l.fallback != nil {
+				return nil, fmt.Errorf("%w: port %s of virtual host %s is taken", errSetupServer, p, vhost.Name)
+			}
+			l.fallback = handler
+			continue
+		}
+		for _, host := range vhost.Hosts {
+			host = strings.ToLower(host)
+			if _, found := l.hosts[host]; found {
+				return nil, fmt.Errorf("%w: host %s of virtual host %s is taken", errSetupServer, host, vhost.Name)
+			}
+			l.hosts[host] = handler
+		}
+	}
+
+	return listeners, nil
+}
+
+// newVirtualHost creates the engine of the virtual host, its endpoints are
+// served under its base path and its state is registered under its name
+func newVirtualHost(vhost config.VirtualHost, registry *state.Registry) (*gin.Engine, error) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("creating virtual host", map[string]any{"name": vhost.Name, "port": vhost.Port, "hosts": vhost.Hosts, "basePath": vhost.BasePath})
+
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	engine.Use(middleware.LoggerMiddleware())
+	if vhost.Auth != nil {
+		engine.Use(middleware.AuthMiddleware(vhost.Auth))
+	}
+
+	basePath := strings.TrimSuffix(vhost.BasePath, "/")
+	endpoints := make([]config.Endpoint, 0, len(vhost.Endpoints))
+	for _, endpoint := range vhost.Endpoints {
+		endpoint.Path = basePath + endpoint.Path
+		if endpoint.Job != nil {
+			job := *endpoint.Job
+			if job.StatusPath != "" {
+				job.StatusPath = basePath + job.StatusPath
+			}
+			if job.ResultPath != "" {
+				job.ResultPath = basePath + job.ResultPath
+			}
+			endpoint.Job = &job
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	cfg := &config.APIConfig{Endpoints: endpoints}
+	if err := router.SetupRoutes(engine, cfg, registry.Scope(vhost.Name+"/")); err != nil {
+		return nil, err
+	}
+
+	return engine, nil
+}
+
+// virtualHostNames returns the hosts of the virtual hosts, the names of the
+// generated server certificate
+func virtualHostNames(hosts []config.VirtualHost) []string {
+	var names []string
+	for _, vhost := range hosts {
+		for _, host := range vhost.Hosts {
+			names = append(names, strings.ToLower(host))
+		}
+	}
+	return names
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mock-server/internal/config"
+	"mock-server/internal/state"
+
+	"github.com/gin-gonic/gin"
+)
+
+// vendorHost returns a virtual host serving its name at /name
+func vendorHost(t *testing.T, name, port string, hosts ...string) config.VirtualHost {
+	t.Helper()
+
+	file := writeFile(t, name+".json", `{"vendor":"`+name+`"}`)
+	return config.VirtualHost{
+		Name:      name,
+		Port:      port,
+		Hosts:     hosts,
+		Endpoints: []config.Endpoint{{Path: "/name", Method: http.MethodGet, ResponseObjFilePath: file}},
+	}
+}
+
+func TestVirtualHostDispatch(t *testing.T) {
+	main := gin.New()
+	main.GET("/name", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"vendor": "main"}) })
+
+	listeners, err := setupListeners(main, "8080", []config.VirtualHost{
+		vendorHost(t, "vendor-a", "", "api.vendor-a.local"),
+		vendorHost(t, "vendor-b", "", "*.vendor-b.local"),
+		vendorHost(t, "vendor-c", "8081", "api.vendor-c.local"),
+		vendorHost(t, "vendor-d", "8082"),
+	}, state.NewRegistry(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		port   string
+		host   string
+		status int
+		body   string
+	}{
+		{"exact host", "8080", "api.vendor-a.local", http.StatusOK, `{"vendor":"vendor-a"}`},
+		{"host with port and upper case", "8080", "API.Vendor-A.local:8080", http.StatusOK, `{"vendor":"vendor-a"}`},
+		{"wildcard subdomain", "8080", "eu.api.vendor-b.local", http.StatusOK, `{"vendor":"vendor-b"}`},
+		{"wildcard needs a subdomain", "8080", "vendor-b.local", http.StatusOK, `{"vendor":"main"}`},
+		{"unknown host on the main port", "8080", "localhost", http.StatusOK, `{"vendor":"main"}`},
+		{"host of its own port", "8081", "api.vendor-c.local", http.StatusOK, `{"vendor":"vendor-c"}`},
+		{"unknown host on its own port", "8081", "api.vendor-a.local", http.StatusNotFound, `{"error":"unknown host"}`},
+		{"any host of a port without hosts", "8082", "localhost", http.StatusOK, `{"vendor":"vendor-d"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/name", nil)
+			req.Host = tt.host
+
+			w := httptest.NewRecorder()
+			listeners[tt.port].handler().ServeHTTP(w, req)
+			if w.Code != tt.status || w.Body.String() != tt.body {
+				t.Errorf("response = %d %s, want %d %s", w.Code, w.Body, tt.status, tt.body)
+			}
+		})
+	}
+}
+
+func TestVirtualHostClashes(t *testing.T) {
+	tests := []struct {
+		name  string
+		hosts []config.VirtualHost
+	}{
+		{"main port without hosts", []config.VirtualHost{vendorHost(t, "vendor-a", "")}},
+		{"own port without hosts twice", []config.VirtualHost{vendorHost(t, "vendor-a", "8081"), vendorHost(t, "vendor-b", "8081")}},
+		{"same host on a port", []config.VirtualHost{vendorHost(t, "vendor-a", "", "api.local"), vendorHost(t, "vendor-b", "", "API.local")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := setupListeners(gin.New(), "8080", tt.hosts, state.NewRegistry("")); !errors.Is(err, errSetupServer) {
+				t.Errorf("setupListeners() error = %v, want %v", err, errSetupServer)
+			}
+		})
+	}
+
+	// The same host on different ports is no clash
+	hosts := []config.VirtualHost{vendorHost(t, "vendor-a", "", "api.local"), vendorHost(t, "vendor-b", "8081", "api.local")}
+	if _, err := setupListeners(gin.New(), "8080", hosts, state.NewRegistry("")); err != nil {
+		t.Errorf("setupListeners() error = %v", err)
+	}
+}
+
+func TestVirtualHostAuth(t *testing.T) {
+	vhost := vendorHost(t, "vendor-a", "8081")
+	vhost.BasePath = "/v1"
+	vhost.Auth = &config.Auth{Type: "bearer", Tokens: []string{"token-a"}}
+
+	listeners, err := setupListeners(gin.New(), "8080", []config.VirtualHost{vhost}, state.NewRegistry(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for token, status := range map[string]int{"": http.StatusUnauthorized, "token-a": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/name", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		w := httptest.NewRecorder()
+		listeners["8081"].handler().ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("token %q: status = %d, want %d", token, w.Code, status)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"mock-server/pkg/logger"
+)
+
+var (
+	errSaveState    = errors.New("failed to save mock state")
+	errRestoreState = errors.New("failed to restore mock state")
+
+	// ErrInvalidSnapshot is returned for a snapshot with unknown or undecodable items
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+)
+
+// Stateful is implemented by everything holding mock state which survives a
+// restart. Decode returns the function replacing the state of the item with
+// the decoded state, so that a snapshot is applied only when all its items decode.
+type Stateful interface {
+	Snapshot() (json.RawMessage, error)
+	Decode(data json.RawMessage) (restore func(), err error)
+}
+
+// Restore replaces the state of the item with the given state
+func Restore(item Stateful, data json.RawMessage) error {
+	restore, err := item.Decode(data)
+	if err != nil {
+		return err
+	}
+	restore()
+	return nil
+}
+
+// Snapshot is the state of all registered items by name
+type Snapshot map[string]json.RawMessage
+
+// Registry keeps track of the stateful items and persists them to a file
+type Registry struct {
+	mu    sync.Mutex
+	file  string
+	items map[string]Stateful
+
+	// saveMu serializes the saves of the ticker, the admin operation and the shutdown
+	saveMu sync.Mutex
+
+	// parent is the registry the items of a scope are registered in
+	parent *Registry
+	prefix string
+}
+
+// NewRegistry creates a registry persisting to the given file, an empty
+// file disables the persistence
+func NewRegistry(file string) *Registry {
+	return &Registry{
+		file:  file,
+		items: make(map[string]Stateful),
+	}
+}
+
+// Register adds a stateful item under the given name
+func (r *Registry) Register(name string, item Stateful) {
+	if r.parent != nil {
+		r.parent.Register(r.prefix+name, item)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items[name] = item
+}
+
+// Scope returns a registry registering its items in r, their names prefixed
+// with the given prefix
+func (r *Registry) Scope(prefix string) *Registry {
+	return &Registry{parent: r, prefix: prefix}
+}
+
+// Persistent reports whether the registry persists to a file
+func (r *Registry) Persistent() bool {
+	return r.file != ""
+}
+
+// Snapshot returns the state of all the registered items
+func (r *Registry) Snapshot() (Snapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make(Snapshot, len(r.items))
+	for name, item := range r.items {
+		data, err := item.Snapshot()
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to snapshot %s", name), err)
+		}
+		snapshot[name] = data
+	}
+
+	return snapshot, nil
+}
+
+// Restore restores the registered items from the snapshot, items missing
+// from the snapshot keep their state. Nothing is restored when an item of the
+// snapshot is unknown or can not be decoded.
+func (r *Registry) Restore(snapshot Snapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	restores := make([]func(), 0, len(names))
+	for _, name := range names {
+		item, ok := r.items[name]
+		if !ok {
+			return fmt.Errorf("%w: unknown item %s", ErrInvalidSnapshot, name)
+		}
+		restore, err := item.Decode(snapshot[name])
+		if err != nil {
+			return fmt.Errorf("%w: failed to decode %s: %w", ErrInvalidSnapshot, name, err)
+		}
+		restores = append(restores, restore)
+	}
+
+	for _, restore := range restores {
+		restore()
+	}
+
+	return nil
+}
+
+// Save writes the snapshot of all the registered items to the file
+func (r *Registry) Save() (Snapshot, error) {
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+
+	snapshot, err := r.Snapshot()
+	if err != nil {
+		return nil, errors.Join(errSaveState, err)
+	}
+
+	if !r.Persistent() {
+		return snapshot, nil
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, errors.Join(errSaveState, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.file), 0o755); err != nil {
+		return nil, errors.Join(errSaveState, err)
+	}
+
+	// Write to a temporary file first so that a crash never leaves a partial snapshot
+	tmpFile := r.file + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0o644); err != nil {
+		return nil, errors.Join(errSaveState, err)
+	}
+	if err := os.Rename(tmpFile, r.file); err != nil {
+		return nil, errors.Join(errSaveState, err)
+	}
+
+	return snapshot, nil
+}
+
+// Load restores the registered items from the file, a missing file is not an error
+func (r *Registry) Load() error {
+	if !r.Persistent() {
+		return nil
+	}
+
+	data, err := os.ReadFile(r.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.Join(errRestoreState, err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return errors.Join(errRestoreState, err)
+	}
+
+	// The endpoints may have changed since the file was written, their items are dropped
+	r.mu.Lock()
+	for name := range snapshot {
+		if _, ok := r.items[name]; !ok {
+			logger.GetLogger().WarnW("unknown item in snapshot file", errRestoreState, map[string]any{"name": name})
+			delete(snapshot, name)
+		}
+	}
+	r.mu.Unlock()
+
+	return r.Restore(snapshot)
+}
@@ -0,0 +1,84 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// counter is a stateful item holding a number
+type counter struct {
+	value int
+}
+
+func (c *counter) Snapshot() (json.RawMessage, error) {
+	return json.Marshal(c.value)
+}
+
+func (c *counter) Decode(data json.RawMessage) (func(), error) {
+	var value int
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return func() { c.value = value }, nil
+}
+
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot Snapshot
+		invalid  bool
+		want     [2]int
+	}{
+		{"all items", Snapshot{"a": json.RawMessage(`10`), "b": json.RawMessage(`20`)}, false, [2]int{10, 20}},
+		{"missing item keeps its state", Snapshot{"b": json.RawMessage(`20`)}, false, [2]int{1, 20}},
+		{"item not decoded", Snapshot{"a": json.RawMessage(`10`), "b": json.RawMessage(`"x"`)}, true, [2]int{1, 2}},
+		{"unknown item", Snapshot{"a": json.RawMessage(`10`), "c": json.RawMessage(`30`)}, true, [2]int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := &counter{value: 1}, &counter{value: 2}
+			r := NewRegistry("")
+			r.Register("a", a)
+			r.Register("b", b)
+
+			err := r.Restore(tt.snapshot)
+			if got := errors.Is(err, ErrInvalidSnapshot); got != tt.invalid {
+				t.Fatalf("Restore() error = %v, want an invalid snapshot %v", err, tt.invalid)
+			}
+			if got := [2]int{a.value, b.value}; got != tt.want {
+				t.Errorf("values = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state", "mock-state.json")
+
+	saved := NewRegistry(file)
+	saved.Register("a", &counter{value: 10})
+	saved.Scope("vendor/").Register("b", &counter{value: 20})
+	// The endpoint of this item is gone when the file is loaded
+	saved.Register("removed", &counter{value: 30})
+	if _, err := saved.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := &counter{}, &counter{}
+	loaded := NewRegistry(file)
+	loaded.Register("a", a)
+	loaded.Scope("vendor/").Register("b", b)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if a.value != 10 || b.value != 20 {
+		t.Errorf("loaded values = %d, %d, want 10, 20", a.value, b.value)
+	}
+
+	if err := NewRegistry(filepath.Join(t.TempDir(), "missing.json")).Load(); err != nil {
+		t.Errorf("Load() of a missing file error = %v", err)
+	}
+}
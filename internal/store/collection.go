@@ -0,0 +1,223 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+
+	"mock-server/internal/dataset"
+	"mock-server/internal/state"
+)
+
+var (
+	// ErrDuplicateID is returned when a record is created with the id of another record
+	ErrDuplicateID = errors.New("record id already exists")
+	// ErrInvalidID is returned when a record is created with an id which is not a string, number or boolean
+	ErrInvalidID = errors.New("record id must be a string, number or boolean")
+)
+
+// Collection is an in-memory collection of records identified by idField
+type Collection struct {
+	mu         sync.RWMutex
+	idField    string
+	records    []map[string]any
+	nextID     int
+	numericIDs bool
+}
+
+var (
+	_ dataset.Source = (*Collection)(nil)
+	_ state.Stateful = (*Collection)(nil)
+)
+
+// NewCollection creates a collection seeded with the given records
+func NewCollection(idField string, seed []any) *Collection {
+	c := &Collection{
+		idField:    idField,
+		records:    make([]map[string]any, 0, len(seed)),
+		nextID:     1,
+		numericIDs: true,
+	}
+
+	for _, r := range seed {
+		record, ok := dataset.DeepCopy(r).(map[string]any)
+		if !ok {
+			continue
+		}
+		c.track(record[idField])
+		c.records = append(c.records, record)
+	}
+
+	return c
+}
+
+// Records returns the records of the collection
+func (c *Collection) Records() []any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	records := make([]any, len(c.records))
+	for i, r := range c.records {
+		records[i] = r
+	}
+	return records
+}
+
+// Get returns the record with the given id
+func (c *Collection) Get(id string) (map[string]any, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	i := c.indexOf(id)
+	if i < 0 {
+		return nil, false
+	}
+	return c.records[i], true
+}
+
+// Create adds the record to the collection, a new id is assigned when the record has none
+func (c *Collection) Create(record map[string]any) (map[string]any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if id, ok := record[c.idField]; ok {
+		switch id.(type) {
+		case string, float64, bool:
+		default:
+			return nil, ErrInvalidID
+		}
+		if c.indexOf(dataset.IDString(id)) >= 0 {
+			return nil, ErrDuplicateID
+		}
+	} else if c.numericIDs {
+		record[c.idField] = float64(c.nextID)
+	} else {
+		record[c.idField] = strconv.Itoa(c.nextID)
+	}
+	c.track(record[c.idField])
+
+	c.records = append(c.records, record)
+	return record, nil
+}
+
+// Replace replaces the record with the given id, the id of the record is kept
+func (c *Collection) Replace(id string, record map[string]any) (map[string]any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.indexOf(id)
+	if i < 0 {
+		return nil, false
+	}
+
+	record[c.idField] = c.records[i][c.idField]
+	c.records[i] = record
+	return record, true
+}
+
+// Update merges the given fields into the record with the given id
+func (c *Collection) Update(id string, fields map[string]any) (map[string]any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.indexOf(id)
+	if i < 0 {
+		return nil, false
+	}
+
+	record := make(map[string]any, len(c.records[i])+len(fields))
+	for k, v := range c.records[i] {
+		record[k] = v
+	}
+	for k, v := range fields {
+		record[k] = v
+	}
+	record[c.idField] = c.records[i][c.idField]
+
+	c.records[i] = record
+	return record, true
+}
+
+// Delete removes the record with the given id
+func (c *Collection) Delete(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.indexOf(id)
+	if i < 0 {
+		return false
+	}
+
+	c.records = append(c.records[:i:i], c.records[i+1:]...)
+	return true
+}
+
+func (c *Collection) indexOf(id string) int {
+	for i, r := range c.records {
+		if value, ok := r[c.idField]; ok && dataset.IDString(value) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// track keeps the next generated id above the ids in the collection
+func (c *Collection) track(id any) {
+	switch v := id.(type) {
+	case float64:
+		if int(v) >= c.nextID {
+			c.nextID = int(v) + 1
+		}
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			if n >= c.nextID {
+				c.nextID = n + 1
+			}
+		}
+		c.numericIDs = false
+	case nil:
+	default:
+		c.numericIDs = false
+	}
+}
+
+// collectionState is the persisted state of a collection
+type collectionState struct {
+	Records    []map[string]any `json:"records"`
+	NextID     int              `json:"nextID"`
+	NumericIDs bool             `json:"numericIDs"`
+}
+
+// Snapshot returns the state of the collection
+func (c *Collection) Snapshot() (json.RawMessage, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return json.Marshal(collectionState{
+		Records:    c.records,
+		NextID:     c.nextID,
+		NumericIDs: c.numericIDs,
+	})
+}
+
+// Decode decodes the given state and returns the function replacing the state
+// of the collection with it
+func (c *Collection) Decode(data json.RawMessage) (func(), error) {
+	var s collectionState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.records = s.Records
+		if c.records == nil {
+			c.records = []map[string]any{}
+		}
+		c.nextID = s.NextID
+		c.numericIDs = s.NumericIDs
+	}, nil
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"mock-server/internal/dataset"
+	"mock-server/internal/state"
+)
+
+func TestCollectionGet(t *testing.T) {
+	c := NewCollection("id", []any{
+		map[string]any{"id": float64(1), "name": "a"},
+		map[string]any{"id": float64(1000000), "name": "b"},
+		map[string]any{"id": "usr-1", "name": "c"},
+	})
+
+	tests := []struct {
+		name     string
+		id       string
+		wantName string
+		found    bool
+	}{
+		{"small number", "1", "a", true},
+		{"large number", "1000000", "b", true},
+		{"string", "usr-1", "c", true},
+		{"missing", "2", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, found := c.Get(tt.id)
+			if found != tt.found {
+				t.Fatalf("Get(%q) found = %v, want %v", tt.id, found, tt.found)
+			}
+			if found && record["name"] != tt.wantName {
+				t.Errorf("Get(%q) = %v, want name %q", tt.id, record, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestCollectionCreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		seed   []any
+		record map[string]any
+		want   any
+	}{
+		{"number after the largest", []any{map[string]any{"id": float64(7)}}, map[string]any{}, float64(8)},
+		{"first of an empty collection", nil, map[string]any{}, float64(1)},
+		{"string after the largest", []any{map[string]any{"id": "12"}}, map[string]any{}, "13"},
+		{"given id kept", []any{map[string]any{"id": float64(1)}}, map[string]any{"id": "x"}, "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCollection("id", tt.seed)
+			created, err := c.Create(tt.record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created["id"] != tt.want {
+				t.Fatalf("created id = %v, want %v", created["id"], tt.want)
+			}
+			if _, found := c.Get(dataset.IDString(tt.want)); !found {
+				t.Errorf("created record %v not found", tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectionReplaceUpdateDelete(t *testing.T) {
+	c := NewCollection("id", []any{
+		map[string]any{"id": float64(1), "name": "a", "role": "admin"},
+		map[string]any{"id": float64(2), "name": "b"},
+	})
+
+	replaced, found := c.Replace("1", map[string]any{"id": float64(9), "name": "x"})
+	if !found || !reflect.DeepEqual(replaced, map[string]any{"id": float64(1), "name": "x"}) {
+		t.Fatalf("Replace() = %v, %v", replaced, found)
+	}
+
+	updated, found := c.Update("2", map[string]any{"role": "viewer"})
+	if !found || !reflect.DeepEqual(updated, map[string]any{"id": float64(2), "name": "b", "role": "viewer"}) {
+		t.Fatalf("Update() = %v, %v", updated, found)
+	}
+
+	if !c.Delete("1") {
+		t.Fatal("Delete(1) = false")
+	}
+	if c.Delete("1") {
+		t.Error("second Delete(1) = true")
+	}
+	if _, found := c.Update("3", map[string]any{}); found {
+		t.Error("Update of a missing record found it")
+	}
+	if got := len(c.Records()); got != 1 {
+		t.Errorf("records = %d, want 1", got)
+	}
+}
+
+func TestCollectionSeedIsCopied(t *testing.T) {
+	seed := []any{map[string]any{"id": float64(1), "name": "a"}}
+
+	c := NewCollection("id", seed)
+	c.Update("1", map[string]any{"name": "b"})
+	c.Delete("1")
+
+	if got := seed[0].(map[string]any)["name"]; got != "a" {
+		t.Errorf("seed changed to %v", got)
+	}
+}
+
+func TestCollectionSnapshotRestore(t *testing.T) {
+	c := NewCollection("id", []any{map[string]any{"id": float64(1)}})
+	c.Create(map[string]any{})
+
+	data, err := c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewCollection("id", nil)
+	if err := state.Restore(restored, data); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(restored.Records()); got != 2 {
+		t.Fatalf("restored records = %d, want 2", got)
+	}
+
+	// The ids keep counting from the snapshot
+	if created, _ := restored.Create(map[string]any{}); created["id"] != float64(3) {
+		t.Errorf("created id after restore = %v, want 3", created["id"])
+	}
+}
+
+func TestCollectionCreateInvalidID(t *testing.T) {
+	c := NewCollection("id", []any{map[string]any{"id": float64(5)}})
+
+	tests := []struct {
+		name   string
+		record map[string]any
+		err    error
+	}{
+		{"existing number", map[string]any{"id": float64(5)}, ErrDuplicateID},
+		{"existing number as a string", map[string]any{"id": "5"}, ErrDuplicateID},
+		{"null", map[string]any{"id": nil}, ErrInvalidID},
+		{"object", map[string]any{"id": map[string]any{"n": float64(1)}}, ErrInvalidID},
+		{"array", map[string]any{"id": []any{float64(1)}}, ErrInvalidID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Create(tt.record); !errors.Is(err, tt.err) {
+				t.Errorf("Create(%v) error = %v, want %v", tt.record, err, tt.err)
+			}
+		})
+	}
+
+	if got := len(c.Records()); got != 1 {
+		t.Errorf("records = %d, want 1", got)
+	}
+}
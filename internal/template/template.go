@@ -0,0 +1,95 @@
+package template
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// placeholder matches {{path.name}}, {{query.name}} and {{header.name}}
+var placeholder = regexp.MustCompile(`\{\{\s*(path|query|header)\.([^}\s]+)\s*\}\}`)
+
+// Request holds the values of the placeholders, *gin.Context is one
+type Request interface {
+	Param(key string) string
+	Query(key string) string
+	GetHeader(key string) string
+}
+
+// requestValues is a copy of the values of a request
+type requestValues struct {
+	params gin.Params
+	query  url.Values
+	header http.Header
+}
+
+// Snapshot copies the values of the request. gin reuses the context once the
+// handler has returned, the snapshot renders the placeholders after that.
+func Snapshot(c *gin.Context) Request {
+	return &requestValues{
+		params: append(gin.Params(nil), c.Params...),
+		query:  c.Request.URL.Query(),
+		header: c.Request.Header.Clone(),
+	}
+}
+
+func (r *requestValues) Param(key string) string     { return r.params.ByName(key) }
+func (r *requestValues) Query(key string) string     { return r.query.Get(key) }
+func (r *requestValues) GetHeader(key string) string { return r.header.Get(key) }
+
+// Render returns a copy of the given JSON value with the placeholders in its
+// strings replaced by the values of the request
+func Render(value any, c Request) any {
+	switch v := value.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[key] = Render(item, c)
+		}
+		return m
+	case []any:
+		arr := make([]any, len(v))
+		for i, item := range v {
+			arr[i] = Render(item, c)
+		}
+		return arr
+	case string:
+		return RenderString(v, c)
+	default:
+		return v
+	}
+}
+
+// RenderString replaces the placeholders in the given string
+func RenderString(s string, c Request) string {
+	return render(s, c, func(value string) string { return value })
+}
+
+// RenderXML replaces the placeholders in the given XML document, the values
+// are escaped so that they can not break the markup
+func RenderXML(s string, c Request) string {
+	return render(s, c, func(value string) string {
+		var escaped strings.Builder
+		_ = xml.EscapeText(&escaped, []byte(value))
+		return escaped.String()
+	})
+}
+
+func render(s string, c Request, escape func(string) string) string {
+	return placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		parts := placeholder.FindStringSubmatch(match)
+
+		switch parts[1] {
+		case "path":
+			return escape(c.Param(parts[2]))
+		case "query":
+			return escape(c.Query(parts[2]))
+		default:
+			return escape(c.GetHeader(parts[2]))
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"mock-server/internal/config"
+	"mock-server/pkg/logger"
+)
+
+const (
+	// validity is how long the generated certificates are valid
+	validity = 365 * 24 * time.Hour
+
+	caFile        = "ca.pem"
+	caKeyFile     = "ca-key.pem"
+	clientFile    = "client.pem"
+	clientKeyFile = "client-key.pem"
+)
+
+var (
+	errLoadTLS = errors.New("failed to load tls config")
+)
+
+// defaultHosts are the names of the generated server certificate
+var defaultHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// clientAuthTypes are the client certificate checks of the clientAuth values
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":         tls.NoClientCert,
+	"none":     tls.NoClientCert,
+	"optional": tls.VerifyClientCertIfGiven,
+	"require":  tls.RequireAndVerifyClientCert,
+}
+
+// Load returns the TLS config of the servers. Without a certificate file a CA
+// is generated, which issues the server certificate, for the hosts of the config
+// and the given virtual hosts, and verifies the clients. The CA written to the
+// output directory is reused on the next start, so the clients keep trusting it.
+func Load(cfg *config.TLS, virtualHosts []string) (*tls.Config, error) {
+	var mockLogger = logger.GetLogger()
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: clientAuthTypes[cfg.ClientAuth],
+	}
+
+	var ca *authority
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.ResolveFilePath(cfg.CertFile), config.ResolveFilePath(cfg.KeyFile))
+		if err != nil {
+			mockLogger.Warn("invalid tls certificate", err)
+			return nil, errors.Join(errLoadTLS, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else {
+		var err error
+		if ca, err = loadAuthority(cfg.OutputDir); err != nil {
+			mockLogger.Warn("invalid tls ca", err)
+			return nil, errors.Join(errLoadTLS, err)
+		}
+
+		hosts := cfg.Hosts
+		if len(hosts) == 0 {
+			hosts = defaultHosts
+		}
+		for _, host := range virtualHosts {
+			if !slices.Contains(hosts, host) {
+				hosts = append(slices.Clip(hosts), host)
+			}
+		}
+		cert, err := ca.issue(hosts[0], hosts, x509.ExtKeyUsageServerAuth)
+		if err != nil {
+			return nil, errors.Join(errLoadTLS, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+
+		if cfg.OutputDir != "" {
+			if err := ca.write(config.ResolveFilePath(cfg.OutputDir)); err != nil {
+				mockLogger.Warn("failed to write tls certificates", err)
+				return nil, errors.Join(errLoadTLS, err)
+			}
+		}
+		mockLogger.InfoW("self-signed tls certificate generated", map[string]any{"hosts": hosts, "outputDir": cfg.OutputDir})
+	}
+
+	if tlsConfig.ClientAuth == tls.NoClientCert {
+		return tlsConfig, nil
+	}
+
+	// The client certificates are verified against the given CA, or the generated one
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if cfg.ClientCAFile != "" {
+		data, err := os.ReadFile(config.ResolveFilePath(cfg.ClientCAFile))
+		if err != nil {
+			mockLogger.Warn("failed to read client ca", err)
+			return nil, errors.Join(errLoadTLS, err)
+		}
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(data) {
+			return nil, errors.Join(errLoadTLS, errors.New("no certificate in client ca file"))
+		}
+	} else {
+		tlsConfig.ClientCAs.AddCert(ca.cert)
+	}
+
+	return tlsConfig, nil
+}
+
+// authority is a generated CA
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// loadAuthority returns the CA written to the output directory, or generates
+// a new one when there is none
+func loadAuthority(outputDir string) (*authority, error) {
+	if outputDir == "" {
+		return newAuthority()
+	}
+
+	dir := config.ResolveFilePath(outputDir)
+	certPEM, err := os.ReadFile(filepath.Join(dir, caFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return newAuthority()
+	} else if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(dir, caKeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		// A CA written without its key can not issue certificates
+		return newAuthority()
+	} else if err != nil {
+		return nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, errors.New("invalid pem in tls output directory")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("ca key is not an ecdsa key")
+	}
+
+	// An expired CA is replaced
+	if time.Now().After(cert.NotAfter) {
+		return newAuthority()
+	}
+
+	return &authority{cert: cert, key: key}, nil
+}
+
+// newAuthority generates a self-signed CA
+func newAuthority() (*authority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{CommonName: "mock-server CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authority{cert: cert, key: key}, nil
+}
+
+// issue returns a certificate signed by the CA for the given hosts
+func (a *authority) issue(name string, hosts []string, usage x509.ExtKeyUsage) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// write writes the CA certificate, for the clients to trust, its key, to reuse
+// the CA, and a client certificate issued by the CA to the directory
+func (a *authority) write(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	caKey, err := x509.MarshalPKCS8PrivateKey(a.key)
+	if err != nil {
+		return err
+	}
+	client, err := a.issue("mock-client", nil, x509.ExtKeyUsageClientAuth)
+	if err != nil {
+		return err
+	}
+	key, err := x509.MarshalPKCS8PrivateKey(client.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	files := map[string]*pem.Block{
+		caFile:        {Type: "CERTIFICATE", Bytes: a.cert.Raw},
+		caKeyFile:     {Type: "PRIVATE KEY", Bytes: caKey},
+		clientFile:    {Type: "CERTIFICATE", Bytes: client.Certificate[0]},
+		clientKeyFile: {Type: "PRIVATE KEY", Bytes: key},
+	}
+	for name, block := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0o600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// serialNumber returns a random certificate serial number
+func serialNumber() *big.Int {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return serial
+}
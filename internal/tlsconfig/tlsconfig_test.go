@@ -0,0 +1,99 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mock-server/internal/config"
+)
+
+// serve starts an HTTPS server with the TLS config loaded from the config
+func serve(t *testing.T, cfg *config.TLS) *httptest.Server {
+	t.Helper()
+
+	tlsConfig, err := Load(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// client returns a client trusting the CA written to the directory, with the
+// client certificate written next to it when withCert is set
+func client(t *testing.T, dir string, withCert bool) *http.Client {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dir, caFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(data)
+
+	tlsConfig := &tls.Config{RootCAs: roots}
+	if withCert {
+		cert, err := tls.LoadX509KeyPair(filepath.Join(dir, clientFile), filepath.Join(dir, clientKeyFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+}
+
+func TestClientAuth(t *testing.T) {
+	tests := []struct {
+		clientAuth string
+		withCert   bool
+		rejected   bool
+	}{
+		{"", false, false},
+		{"optional", false, false},
+		{"optional", true, false},
+		{"require", false, true},
+		{"require", true, false},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		server := serve(t, &config.TLS{OutputDir: dir, ClientAuth: tt.clientAuth})
+
+		resp, err := client(t, dir, tt.withCert).Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if rejected := err != nil; rejected != tt.rejected {
+			t.Errorf("clientAuth %q with a certificate %v: error = %v, want rejected %v", tt.clientAuth, tt.withCert, err, tt.rejected)
+		}
+	}
+}
+
+func TestGeneratedCAIsReused(t *testing.T) {
+	dir := t.TempDir()
+	first := serve(t, &config.TLS{OutputDir: dir, ClientAuth: "require"})
+	trusted := client(t, dir, true)
+
+	// The next start issues a new server certificate from the same CA
+	second := serve(t, &config.TLS{OutputDir: dir, ClientAuth: "require"})
+
+	for _, server := range []*httptest.Server{first, second} {
+		resp, err := trusted.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request with the certificates of the first start failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+}
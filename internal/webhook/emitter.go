@@ -0,0 +1,437 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"mock-server/internal/config"
+	"mock-server/internal/dataset"
+	"mock-server/internal/store"
+	"mock-server/pkg/logger"
+)
+
+const (
+	defaultSubscriptionPath   = "/webhooks"
+	defaultSignatureHeader    = "X-Webhook-Signature"
+	defaultSignatureAlgorithm = "sha256"
+	defaultRetryBackoff       = time.Second
+	defaultTimeout            = 5 * time.Second
+
+	// maxDeliveries is the number of deliveries kept in the delivery log
+	maxDeliveries = 1000
+
+	pendingDelivery   = "pending"
+	deliveredDelivery = "delivered"
+	failedDelivery    = "failed"
+)
+
+var (
+	errCreateEmitter = errors.New("failed to create webhook emitter")
+	errUnknownEvent  = errors.New("unknown webhook event")
+)
+
+// hashes are the hash functions of the signature algorithms
+var hashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// Emitter delivers the webhook events to the subscribed URLs
+type Emitter struct {
+	subscriptionPath string
+	secret           string
+	signatureHeader  string
+	algorithm        string
+	timestampHeader  string
+	maxRetries       int
+	retryBackoff     time.Duration
+	disableAfter     int
+	events           map[string]event
+	subscriptions    *store.Collection
+	client           *http.Client
+
+	// ctx ends the deliveries and their retries when the server stops
+	ctx context.Context
+
+	mu         sync.Mutex
+	deliveries []*Delivery
+	failures   map[string]int
+}
+
+// event is a configured event type and its payload
+type event struct {
+	name     string
+	payload  any
+	schedule time.Duration
+	headers  map[string]string
+}
+
+// Delivery is the delivery of an event to a subscription
+type Delivery struct {
+	ID           string    `json:"id"`
+	EventID      string    `json:"eventId"`
+	Event        string    `json:"event"`
+	Subscription string    `json:"subscription"`
+	URL          string    `json:"url"`
+	Status       string    `json:"status"`
+	Attempts     int       `json:"attempts"`
+	StatusCode   int       `json:"statusCode,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// NewEmitter creates the webhook emitter of the given config
+func NewEmitter(cfg *config.Webhooks) (*Emitter, error) {
+	var mockLogger = logger.GetLogger()
+
+	e := &Emitter{
+		subscriptionPath: defaultSubscriptionPath,
+		secret:           cfg.Secret,
+		signatureHeader:  defaultSignatureHeader,
+		algorithm:        defaultSignatureAlgorithm,
+		timestampHeader:  cfg.TimestampHeader,
+		maxRetries:       cfg.MaxRetries,
+		retryBackoff:     defaultRetryBackoff,
+		disableAfter:     cfg.DisableAfter,
+		events:           make(map[string]event, len(cfg.Events)),
+		subscriptions:    store.NewCollection("id", nil),
+		client:           &http.Client{Timeout: defaultTimeout},
+		failures:         make(map[string]int),
+		ctx:              context.Background(),
+	}
+
+	if cfg.SubscriptionPath != "" {
+		e.subscriptionPath = cfg.SubscriptionPath
+	}
+	if cfg.SignatureHeader != "" {
+		e.signatureHeader = cfg.SignatureHeader
+	}
+	if cfg.SignatureAlgorithm != "" {
+		e.algorithm = cfg.SignatureAlgorithm
+	}
+	if cfg.RetryBackoff != "" {
+		e.retryBackoff, _ = time.ParseDuration(cfg.RetryBackoff)
+	}
+	if cfg.Timeout != "" {
+		e.client.Timeout, _ = time.ParseDuration(cfg.Timeout)
+	}
+
+	for _, ev := range cfg.Events {
+		evt := event{name: ev.Name, headers: ev.Headers, payload: map[string]any{}}
+		if ev.Schedule != "" {
+			evt.schedule, _ = time.ParseDuration(ev.Schedule)
+		}
+
+		if ev.ResponseObjFilePath != "" {
+			data, err := os.ReadFile(config.ResolveFilePath(ev.ResponseObjFilePath))
+			if err != nil {
+				mockLogger.Warn("failed to read webhook payload", err)
+				return nil, errors.Join(errCreateEmitter, err)
+			}
+			if err := json.Unmarshal(data, &evt.payload); err != nil {
+				mockLogger.Warn("invalid webhook payload", err)
+				return nil, errors.Join(errCreateEmitter, err)
+			}
+		}
+
+		e.events[ev.Name] = evt
+	}
+
+	return e, nil
+}
+
+// Subscriptions returns the collection of the subscriptions
+func (e *Emitter) Subscriptions() *store.Collection {
+	return e.subscriptions
+}
+
+// Run sends the scheduled events every schedule until the context is done
+func (e *Emitter) Run(ctx context.Context) {
+	e.ctx = ctx
+
+	for _, evt := range e.events {
+		if evt.schedule <= 0 {
+			continue
+		}
+
+		go func(evt event) {
+			ticker := time.NewTicker(evt.schedule)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if _, _, err := e.Emit(evt.name, nil, ""); err != nil {
+						logger.GetLogger().Warn("failed to emit scheduled webhook", err)
+					}
+				}
+			}
+		}(evt)
+	}
+}
+
+// Emit sends the event to its active subscriptions, or only to the given
+// subscription, and returns the deliveries. A nil payload sends the payload
+// of the event. The deliveries run in the background, the returned channel
+// is closed once they are all done.
+func (e *Emitter) Emit(name string, payload any, subscription string) ([]*Delivery, <-chan struct{}, error) {
+	evt, found := e.events[name]
+	if !found {
+		return nil, nil, fmt.Errorf("%w: %s", errUnknownEvent, name)
+	}
+	if payload == nil {
+		payload = evt.payload
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	eventID := newID("evt")
+	var deliveries []*Delivery
+	for _, r := range e.subscriptions.Records() {
+		sub := r.(map[string]any)
+		id := dataset.IDString(sub["id"])
+		if subscription != "" && id != subscription {
+			continue
+		}
+		if subscription == "" && (!isActive(sub) || !subscribed(sub, name)) {
+			continue
+		}
+
+		url, _ := sub["url"].(string)
+		deliveries = append(deliveries, e.track(&Delivery{
+			ID:           newID("dlv"),
+			EventID:      eventID,
+			Event:        name,
+			Subscription: id,
+			URL:          url,
+			Status:       pendingDelivery,
+			CreatedAt:    time.Now().UTC(),
+		}))
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		secret := e.subscriptionSecret(d.Subscription)
+
+		wg.Add(1)
+		go func(d *Delivery) {
+			defer wg.Done()
+			e.deliver(e.ctx, evt, d, body, secret)
+		}(d)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	return deliveries, done, nil
+}
+
+// deliver POSTs the event to the subscription, retrying the failed attempts
+// with a doubling backoff
+func (e *Emitter) deliver(ctx context.Context, evt event, d *Delivery, body []byte, secret string) {
+	var mockLogger = logger.GetLogger()
+
+	var (
+		backoff    = e.retryBackoff
+		statusCode int
+		err        error
+	)
+	for attempt := 1; attempt <= e.maxRetries+1; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				e.finish(d, failedDelivery, 0, ctx.Err())
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		e.update(d, func(d *Delivery) { d.Attempts = attempt })
+
+		statusCode, err = e.send(ctx, evt, d, body, secret, attempt)
+		if err == nil {
+			e.finish(d, deliveredDelivery, statusCode, nil)
+			mockLogger.InfoW("webhook delivered", map[string]any{"event": d.Event, "url": d.URL, "status": statusCode, "attempts": attempt})
+			return
+		}
+
+		mockLogger.WarnW("webhook delivery attempt failed", err, map[string]any{"event": d.Event, "url": d.URL, "attempt": attempt})
+	}
+
+	e.finish(d, failedDelivery, statusCode, err)
+}
+
+// send makes one delivery attempt, it returns the status code of the subscriber
+func (e *Emitter) send(ctx context.Context, evt event, d *Delivery, body []byte, secret string, attempt int) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mock-server-webhook")
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-Id", d.EventID)
+	req.Header.Set("X-Webhook-Delivery", d.ID)
+	req.Header.Set("X-Webhook-Attempt", strconv.Itoa(attempt))
+	for key, value := range evt.headers {
+		req.Header.Set(key, value)
+	}
+
+	if secret != "" {
+		signed := body
+		if e.timestampHeader != "" {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set(e.timestampHeader, timestamp)
+			signed = append([]byte(timestamp+"."), body...)
+		}
+		req.Header.Set(e.signatureHeader, e.algorithm+"="+e.sign(signed, secret))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// sign returns the hex HMAC of the body
+func (e *Emitter) sign(body []byte, secret string) string {
+	mac := hmac.New(hashes[e.algorithm], []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// finish records the outcome of the delivery. The subscriptions failing
+// disableAfter deliveries in a row are deactivated.
+func (e *Emitter) finish(d *Delivery, status string, statusCode int, err error) {
+	var mockLogger = logger.GetLogger()
+
+	e.update(d, func(d *Delivery) {
+		d.Status = status
+		d.StatusCode = statusCode
+		d.Error = ""
+		if err != nil {
+			d.Error = err.Error()
+		}
+	})
+
+	e.mu.Lock()
+	if status == deliveredDelivery {
+		delete(e.failures, d.Subscription)
+		e.mu.Unlock()
+		return
+	}
+	e.failures[d.Subscription]++
+	failures := e.failures[d.Subscription]
+	e.mu.Unlock()
+
+	mockLogger.WarnW("webhook delivery failed", err, map[string]any{"event": d.Event, "url": d.URL})
+
+	if e.disableAfter > 0 && failures >= e.disableAfter {
+		e.subscriptions.Update(d.Subscription, map[string]any{"active": false})
+		mockLogger.InfoW("webhook subscription disabled", map[string]any{"subscription": d.Subscription, "failures": failures})
+	}
+}
+
+// track adds the delivery to the delivery log, dropping the oldest ones
+func (e *Emitter) track(d *Delivery) *Delivery {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.deliveries = append(e.deliveries, d)
+	if len(e.deliveries) > maxDeliveries {
+		e.deliveries = e.deliveries[len(e.deliveries)-maxDeliveries:]
+	}
+	return d
+}
+
+// update changes the delivery under the lock of the delivery log
+func (e *Emitter) update(d *Delivery, fn func(d *Delivery)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	fn(d)
+}
+
+// Deliveries returns a copy of the delivery log, newest first
+func (e *Emitter) Deliveries() []Delivery {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	deliveries := make([]Delivery, 0, len(e.deliveries))
+	for i := len(e.deliveries) - 1; i >= 0; i-- {
+		deliveries = append(deliveries, *e.deliveries[i])
+	}
+	return deliveries
+}
+
+// subscriptionSecret returns the secret signing the deliveries of the
+// subscription, its own secret or else the configured one
+func (e *Emitter) subscriptionSecret(id string) string {
+	if sub, found := e.subscriptions.Get(id); found {
+		if secret, ok := sub["secret"].(string); ok && secret != "" {
+			return secret
+		}
+	}
+	return e.secret
+}
+
+// isActive reports whether the subscription receives the events
+func isActive(sub map[string]any) bool {
+	active, ok := sub["active"].(bool)
+	return !ok || active
+}
+
+// subscribed reports whether the subscription receives the named event, the
+// subscriptions without events receive all of them
+func subscribed(sub map[string]any, name string) bool {
+	events, ok := sub["events"].([]any)
+	if !ok || len(events) == 0 {
+		return true
+	}
+	for _, e := range events {
+		if e == name || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// newID returns a random identifier with the given prefix
+func newID(prefix string) string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return prefix + "_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,341 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"mock-server/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// received is a request received by a subscriber
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// subscriber is a webhook receiver answering the given status codes in turn,
+// then 200
+type subscriber struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []received
+}
+
+func newSubscriber(t *testing.T, statuses ...int) *subscriber {
+	t.Helper()
+
+	s := &subscriber{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, received{header: r.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		s.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *subscriber) received() []received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]received{}, s.requests...)
+}
+
+// newTestEmitter returns an emitter of the order.created and order.deleted events
+func newTestEmitter(t *testing.T, cfg config.Webhooks) *Emitter {
+	t.Helper()
+
+	cfg.Events = []config.WebhookEvent{{Name: "order.created"}, {Name: "order.deleted"}}
+	if cfg.RetryBackoff == "" {
+		cfg.RetryBackoff = "1ms"
+	}
+	e, err := NewEmitter(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// emit emits the event and waits for its deliveries
+func emit(t *testing.T, e *Emitter, name string, payload any) {
+	t.Helper()
+
+	_, done, err := e.Emit(name, payload, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deliveries not done")
+	}
+}
+
+func hexMAC(h func() hash.Hash, secret string, data []byte) string {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSignature(t *testing.T) {
+	payload := map[string]any{"id": "ord_1"}
+	body, _ := json.Marshal(payload)
+
+	tests := []struct {
+		name      string
+		cfg       config.Webhooks
+		secret    string
+		header    string
+		timestamp bool
+		want      func(timestamp string) string
+	}{
+		{
+			name:   "sha256 by default",
+			cfg:    config.Webhooks{Secret: "s3cret"},
+			header: "X-Webhook-Signature",
+			want:   func(string) string { return "sha256=" + hexMAC(sha256.New, "s3cret", body) },
+		},
+		{
+			name:   "sha1",
+			cfg:    config.Webhooks{Secret: "s3cret", SignatureAlgorithm: "sha1", SignatureHeader: "X-Hub-Signature"},
+			header: "X-Hub-Signature",
+			want:   func(string) string { return "sha1=" + hexMAC(sha1.New, "s3cret", body) },
+		},
+		{
+			name:   "sha512",
+			cfg:    config.Webhooks{Secret: "s3cret", SignatureAlgorithm: "sha512"},
+			header: "X-Webhook-Signature",
+			want:   func(string) string { return "sha512=" + hexMAC(sha512.New, "s3cret", body) },
+		},
+		{
+			name:      "signed timestamp",
+			cfg:       config.Webhooks{Secret: "s3cret", TimestampHeader: "X-Webhook-Timestamp"},
+			header:    "X-Webhook-Signature",
+			timestamp: true,
+			want: func(timestamp string) string {
+				return "sha256=" + hexMAC(sha256.New, "s3cret", append([]byte(timestamp+"."), body...))
+			},
+		},
+		{
+			name:   "secret of the subscription",
+			cfg:    config.Webhooks{Secret: "s3cret"},
+			secret: "own",
+			header: "X-Webhook-Signature",
+			want:   func(string) string { return "sha256=" + hexMAC(sha256.New, "own", body) },
+		},
+		{
+			name:   "unsigned without a secret",
+			cfg:    config.Webhooks{},
+			header: "X-Webhook-Signature",
+			want:   func(string) string { return "" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := newSubscriber(t)
+			e := newTestEmitter(t, tt.cfg)
+			record := map[string]any{"url": sub.URL}
+			if tt.secret != "" {
+				record["secret"] = tt.secret
+			}
+			e.Subscriptions().Create(record)
+
+			emit(t, e, "order.created", payload)
+
+			requests := sub.received()
+			if len(requests) != 1 {
+				t.Fatalf("received %d requests, want 1", len(requests))
+			}
+			if !bytes.Equal(requests[0].body, body) {
+				t.Errorf("body = %s, want %s", requests[0].body, body)
+			}
+
+			timestamp := requests[0].header.Get("X-Webhook-Timestamp")
+			if tt.timestamp && timestamp == "" {
+				t.Error("no timestamp header")
+			}
+			if got, want := requests[0].header.Get(tt.header), tt.want(timestamp); got != want {
+				t.Errorf("%s = %q, want %q", tt.header, got, want)
+			}
+			if got := requests[0].header.Get("X-Webhook-Event"); got != "order.created" {
+				t.Errorf("X-Webhook-Event = %q", got)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		statuses   []int
+		status     string
+		attempts   int
+	}{
+		{"delivered first time", 2, nil, deliveredDelivery, 1},
+		{"delivered on retry", 2, []int{500, 503}, deliveredDelivery, 3},
+		{"failed after the retries", 1, []int{500, 500, 500}, failedDelivery, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := newSubscriber(t, tt.statuses...)
+			e := newTestEmitter(t, config.Webhooks{MaxRetries: tt.maxRetries})
+			e.Subscriptions().Create(map[string]any{"url": sub.URL})
+
+			emit(t, e, "order.created", nil)
+
+			deliveries := e.Deliveries()
+			if len(deliveries) != 1 {
+				t.Fatalf("deliveries = %d, want 1", len(deliveries))
+			}
+			if d := deliveries[0]; d.Status != tt.status || d.Attempts != tt.attempts {
+				t.Errorf("delivery %s after %d attempts, want %s after %d", d.Status, d.Attempts, tt.status, tt.attempts)
+			}
+			if got := len(sub.received()); got != tt.attempts {
+				t.Errorf("received %d requests, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestDisableAfter(t *testing.T) {
+	sub := newSubscriber(t, 500, 500, 500)
+	e := newTestEmitter(t, config.Webhooks{DisableAfter: 2})
+	created, _ := e.Subscriptions().Create(map[string]any{"url": sub.URL})
+
+	emit(t, e, "order.created", nil)
+	emit(t, e, "order.created", nil)
+	emit(t, e, "order.created", nil)
+
+	if got := len(sub.received()); got != 2 {
+		t.Errorf("received %d requests, want 2 before the subscription is disabled", got)
+	}
+	record, _ := e.Subscriptions().Get("1")
+	if record["active"] != false {
+		t.Errorf("subscription %v still active", created)
+	}
+}
+
+func TestSubscribedEvents(t *testing.T) {
+	all := newSubscriber(t)
+	created := newSubscriber(t)
+	inactive := newSubscriber(t)
+
+	e := newTestEmitter(t, config.Webhooks{})
+	e.Subscriptions().Create(map[string]any{"url": all.URL})
+	e.Subscriptions().Create(map[string]any{"url": created.URL, "events": []any{"order.created"}})
+	e.Subscriptions().Create(map[string]any{"url": inactive.URL, "active": false})
+
+	emit(t, e, "order.deleted", nil)
+
+	if got := len(all.received()); got != 1 {
+		t.Errorf("subscription of all events received %d requests, want 1", got)
+	}
+	if got := len(created.received()); got != 0 {
+		t.Errorf("subscription of another event received %d requests, want 0", got)
+	}
+	if got := len(inactive.received()); got != 0 {
+		t.Errorf("inactive subscription received %d requests, want 0", got)
+	}
+
+	if _, _, err := e.Emit("order.unknown", nil, ""); err == nil {
+		t.Error("unknown event emitted")
+	}
+}
+
+func TestLargeSubscriptionID(t *testing.T) {
+	sub := newSubscriber(t)
+	e := newTestEmitter(t, config.Webhooks{Secret: "s3cret"})
+	e.Subscriptions().Create(map[string]any{"id": float64(1000000), "url": sub.URL, "secret": "own"})
+
+	deliveries, done, err := e.Emit("order.created", map[string]any{}, "1000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	if len(deliveries) != 1 || deliveries[0].Subscription != "1000000" {
+		t.Fatalf("deliveries = %v, want one to subscription 1000000", deliveries)
+	}
+	requests := sub.received()
+	if want := "sha256=" + hexMAC(sha256.New, "own", []byte("{}")); len(requests) != 1 || requests[0].header.Get("X-Webhook-Signature") != want {
+		t.Errorf("not signed with the secret of the subscription")
+	}
+}
+
+func TestSubscriptionHandlers(t *testing.T) {
+	sub := newSubscriber(t)
+	e := newTestEmitter(t, config.Webhooks{})
+	engine := gin.New()
+	for _, route := range e.Routes() {
+		engine.Handle(route.Method, route.Path, route.HandlerFunc)
+	}
+
+	send := func(method, target, body string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(method, target, bytes.NewBufferString(body)))
+		var resp map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"relative url rejected", http.MethodPost, "/webhooks", `{"url":"/hook"}`, http.StatusBadRequest},
+		{"unknown event rejected", http.MethodPost, "/webhooks", `{"url":"` + sub.URL + `","events":["nope"]}`, http.StatusBadRequest},
+		{"subscribe", http.MethodPost, "/webhooks", `{"url":"` + sub.URL + `","secret":"own"}`, http.StatusCreated},
+		{"get", http.MethodGet, "/webhooks/1", "", http.StatusOK},
+		{"trigger and wait", http.MethodPost, "/__admin/webhooks/events/order.created?wait=true", `{"id":"ord_1"}`, http.StatusOK},
+		{"trigger unknown event", http.MethodPost, "/__admin/webhooks/events/order.unknown", "", http.StatusNotFound},
+		{"unsubscribe", http.MethodDelete, "/webhooks/1", "", http.StatusNoContent},
+		{"get unsubscribed", http.MethodGet, "/webhooks/1", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		status, resp := send(tt.method, tt.target, tt.body)
+		if status != tt.status {
+			t.Fatalf("%s: status = %d, want %d: %v", tt.name, status, tt.status, resp)
+		}
+		if _, found := resp["secret"]; found {
+			t.Errorf("%s: secret exposed", tt.name)
+		}
+	}
+
+	if got := len(sub.received()); got != 1 {
+		t.Errorf("received %d requests, want 1", got)
+	}
+}
@@ -0,0 +1,248 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"mock-server/internal/admin"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Routes returns the subscription routes and the admin operations of the webhooks
+func (e *Emitter) Routes() gin.RoutesInfo {
+	item := e.subscriptionPath + "/:id"
+	adminPath := admin.BasePath + "/webhooks"
+
+	return gin.RoutesInfo{
+		{Method: http.MethodPost, Path: e.subscriptionPath, HandlerFunc: e.Subscribe},
+		{Method: http.MethodGet, Path: e.subscriptionPath, HandlerFunc: e.List},
+		{Method: http.MethodGet, Path: item, HandlerFunc: e.Get},
+		{Method: http.MethodPatch, Path: item, HandlerFunc: e.Update},
+		{Method: http.MethodDelete, Path: item, HandlerFunc: e.Unsubscribe},
+		{Method: http.MethodPost, Path: adminPath + "/events/:event", HandlerFunc: e.Trigger},
+		{Method: http.MethodGet, Path: adminPath + "/deliveries", HandlerFunc: e.ListDeliveries},
+	}
+}
+
+// subscriptionRequest is the body of the subscription requests
+type subscriptionRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+// Subscribe is the handler function registering a callback URL
+func (e *Emitter) Subscribe(c *gin.Context) {
+	var req subscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request body must be a JSON object"})
+		return
+	}
+	if err := e.validate(req, true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events := make([]any, 0, len(req.Events))
+	for _, event := range req.Events {
+		events = append(events, event)
+	}
+	record := map[string]any{
+		"url":       req.URL,
+		"events":    events,
+		"active":    req.Active == nil || *req.Active,
+		"createdAt": time.Now().UTC().Format(time.RFC3339),
+	}
+	if req.Secret != "" {
+		record["secret"] = req.Secret
+	}
+
+	created, err := e.subscriptions.Create(record)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, public(created))
+}
+
+// List is the handler function listing the subscriptions
+func (e *Emitter) List(c *gin.Context) {
+	records := e.subscriptions.Records()
+	subscriptions := make([]any, 0, len(records))
+	for _, r := range records {
+		subscriptions = append(subscriptions, public(r.(map[string]any)))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": subscriptions})
+}
+
+// Get is the handler function reading a subscription
+func (e *Emitter) Get(c *gin.Context) {
+	record, found := e.subscriptions.Get(c.Param("id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, public(record))
+}
+
+// Update is the handler function changing a subscription, a subscription
+// reactivated this way starts over its count of failed deliveries
+func (e *Emitter) Update(c *gin.Context) {
+	var req subscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request body must be a JSON object"})
+		return
+	}
+	if err := e.validate(req, false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fields := make(map[string]any)
+	if req.URL != "" {
+		fields["url"] = req.URL
+	}
+	if req.Events != nil {
+		events := make([]any, 0, len(req.Events))
+		for _, event := range req.Events {
+			events = append(events, event)
+		}
+		fields["events"] = events
+	}
+	if req.Secret != "" {
+		fields["secret"] = req.Secret
+	}
+	if req.Active != nil {
+		fields["active"] = *req.Active
+	}
+
+	id := c.Param("id")
+	record, found := e.subscriptions.Update(id, fields)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		return
+	}
+	if req.Active != nil && *req.Active {
+		e.mu.Lock()
+		delete(e.failures, id)
+		e.mu.Unlock()
+	}
+
+	c.JSON(http.StatusOK, public(record))
+}
+
+// Unsubscribe is the handler function removing a subscription
+func (e *Emitter) Unsubscribe(c *gin.Context) {
+	if !e.subscriptions.Delete(c.Param("id")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Trigger is the handler function emitting an event. The request body, when
+// present, replaces the payload of the event; the subscription query parameter
+// targets one subscription and wait=true answers once the deliveries are done.
+func (e *Emitter) Trigger(c *gin.Context) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+
+	var payload any
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "request body must be JSON"})
+			return
+		}
+	}
+
+	deliveries, done, err := e.Emit(c.Param("event"), payload, c.Query("subscription"))
+	if errors.Is(err, errUnknownEvent) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusAccepted
+	if c.Query("wait") == "true" {
+		select {
+		case <-done:
+			status = http.StatusOK
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+
+	snapshot := make([]Delivery, 0, len(deliveries))
+	e.mu.Lock()
+	for _, d := range deliveries {
+		snapshot = append(snapshot, *d)
+	}
+	e.mu.Unlock()
+
+	c.JSON(status, gin.H{"event": c.Param("event"), "deliveries": snapshot})
+}
+
+// ListDeliveries is the handler function listing the delivery log, newest
+// first, filtered by the event, subscription and status query parameters
+func (e *Emitter) ListDeliveries(c *gin.Context) {
+	deliveries := make([]Delivery, 0)
+	for _, d := range e.Deliveries() {
+		if event := c.Query("event"); event != "" && d.Event != event {
+			continue
+		}
+		if sub := c.Query("subscription"); sub != "" && d.Subscription != sub {
+			continue
+		}
+		if status := c.Query("status"); status != "" && d.Status != status {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": deliveries})
+}
+
+// validate checks the subscription request, the url is required on creation
+func (e *Emitter) validate(req subscriptionRequest, create bool) error {
+	if req.URL != "" || create {
+		u, err := url.Parse(req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("url must be an absolute http or https URL")
+		}
+	}
+
+	for _, event := range req.Events {
+		if _, found := e.events[event]; !found && event != "*" {
+			return errors.New("unknown event: " + event)
+		}
+	}
+
+	return nil
+}
+
+// public returns the subscription without its secret
+func public(record map[string]any) map[string]any {
+	sub := make(map[string]any, len(record))
+	for k, v := range record {
+		if k != "secret" {
+			sub[k] = v
+		}
+	}
+	return sub
+}
@@ -0,0 +1,396 @@
+package wsmock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mock-server/internal/config"
+	"mock-server/internal/matcher"
+	"mock-server/internal/template"
+	"mock-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	closeDisconnect = "close"
+	abortDisconnect = "abort"
+
+	// closeTimeout is how long the close handshake waits for the close frame of the client
+	closeTimeout = time.Second
+)
+
+var (
+	errCreateServer = errors.New("failed to create websocket server")
+)
+
+// Server serves a websocket endpoint
+type Server struct {
+	path                  string
+	messages              []message
+	echo                  bool
+	rules                 []rule
+	pingInterval          time.Duration
+	pongTimeout           time.Duration
+	close                 *closeFrame
+	disconnectAfter       int
+	disconnectProbability float64
+	disconnectMode        string
+	upgrader              websocket.Upgrader
+}
+
+// message is a message sent delay after the previous one
+type message struct {
+	delay       time.Duration
+	data        any
+	messageType int
+}
+
+// rule answers the inbound messages matching its matchers
+type rule struct {
+	matchers  []matcher.Matcher
+	responses []message
+	close     *closeFrame
+}
+
+// closeFrame closes the connection with a close code, delay after the last message
+type closeFrame struct {
+	code   int
+	reason string
+	delay  time.Duration
+}
+
+// NewServer creates the websocket server of the given endpoint
+func NewServer(endpoint config.Endpoint) (*Server, error) {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("creating websocket server", map[string]any{"endpoint": endpoint.Path})
+
+	cfg := endpoint.WebSocket
+	if cfg == nil {
+		cfg = &config.WebSocket{}
+	}
+
+	s := &Server{
+		path:                  endpoint.Path,
+		echo:                  cfg.Echo,
+		close:                 newCloseFrame(cfg.Close),
+		disconnectAfter:       cfg.DisconnectAfter,
+		disconnectProbability: cfg.DisconnectProbability,
+		disconnectMode:        closeDisconnect,
+		upgrader: websocket.Upgrader{
+			Subprotocols: cfg.Subprotocols,
+			// The mock accepts the connections of any origin
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+
+	if cfg.DisconnectMode != "" {
+		s.disconnectMode = cfg.DisconnectMode
+	}
+	if cfg.PingInterval != "" {
+		s.pingInterval, _ = time.ParseDuration(cfg.PingInterval)
+	}
+	if cfg.PongTimeout != "" {
+		s.pongTimeout, _ = time.ParseDuration(cfg.PongTimeout)
+	}
+
+	var err error
+	if s.messages, err = newMessages(cfg.Messages); err != nil {
+		mockLogger.Warn("invalid websocket message", err)
+		return nil, errors.Join(errCreateServer, err)
+	}
+
+	for _, r := range cfg.Rules {
+		// The rules match the inbound message like the body of a request
+		cfgs := make([]config.Matcher, 0, len(r.Matchers))
+		for _, m := range r.Matchers {
+			m.Location = "body"
+			cfgs = append(cfgs, m)
+		}
+		matchers, err := matcher.NewMatchers(cfgs)
+		if err != nil {
+			mockLogger.Warn("invalid websocket rule", err)
+			return nil, errors.Join(errCreateServer, err)
+		}
+
+		responses, err := newMessages(r.Responses)
+		if err != nil {
+			mockLogger.Warn("invalid websocket message", err)
+			return nil, errors.Join(errCreateServer, err)
+		}
+
+		s.rules = append(s.rules, rule{matchers: matchers, responses: responses, close: newCloseFrame(r.Close)})
+	}
+
+	return s, nil
+}
+
+// newMessages loads the messages of the configs, the message files are read once
+func newMessages(cfgs []config.WebSocketMessage) ([]message, error) {
+	messages := make([]message, 0, len(cfgs))
+
+	for _, cfg := range cfgs {
+		m := message{data: cfg.Data, messageType: websocket.TextMessage}
+		if cfg.Binary {
+			m.messageType = websocket.BinaryMessage
+		}
+		if cfg.Delay != "" {
+			m.delay, _ = time.ParseDuration(cfg.Delay)
+		}
+
+		if cfg.ResponseObjFilePath != "" {
+			data, err := os.ReadFile(config.ResolveFilePath(cfg.ResponseObjFilePath))
+			if err != nil {
+				return nil, err
+			}
+			m.data = string(data)
+		}
+
+		messages = append(messages, m)
+	}
+
+	return messages, nil
+}
+
+func newCloseFrame(cfg *config.WebSocketClose) *closeFrame {
+	if cfg == nil {
+		return nil
+	}
+
+	f := &closeFrame{code: cfg.Code, reason: cfg.Reason}
+	if cfg.Delay != "" {
+		f.delay, _ = time.ParseDuration(cfg.Delay)
+	}
+	return f
+}
+
+// Serve is the handler function of the websocket endpoint
+func (s *Server) Serve(c *gin.Context) {
+	var mockLogger = logger.GetLogger()
+
+	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has answered the request with the error
+		mockLogger.Warn("websocket upgrade failed", err)
+		return
+	}
+
+	sess := &session{server: s, conn: conn, request: template.Snapshot(c), done: make(chan struct{})}
+	defer sess.finish()
+
+	go sess.read()
+	if s.pingInterval > 0 {
+		go sess.ping()
+	}
+
+	// The pushed messages and the close frame, the connection lasts until the client leaves
+	if !sess.sendAll(s.messages) {
+		return
+	}
+	if s.close != nil {
+		sess.closeWith(s.close)
+	}
+	<-sess.done
+}
+
+// session is a websocket connection, the messages are written by one goroutine at a time
+type session struct {
+	server *Server
+	conn   *websocket.Conn
+
+	// request renders the messages, the goroutines of the session outlive the gin context
+	request template.Request
+
+	mu   sync.Mutex
+	sent int
+
+	// awaitingPong is set from a ping until the client answers it
+	awaitingPong atomic.Bool
+
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// read answers the messages of the client until the connection ends
+func (sess *session) read() {
+	defer sess.finish()
+
+	if sess.server.pongTimeout > 0 {
+		sess.conn.SetPongHandler(func(string) error {
+			sess.awaitingPong.Store(false)
+			return sess.conn.SetReadDeadline(time.Time{})
+		})
+	}
+
+	for {
+		messageType, data, err := sess.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if r, found := sess.server.match(data); found {
+			if !sess.sendAll(r.responses) {
+				return
+			}
+			if r.close != nil {
+				sess.closeWith(r.close)
+			}
+			continue
+		}
+
+		if sess.server.echo && !sess.write(messageType, data) {
+			return
+		}
+	}
+}
+
+// ping pings the client every ping interval, a client that does not answer
+// within the pong timeout is dropped by the read deadline
+func (sess *session) ping() {
+	ticker := time.NewTicker(sess.server.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sess.done:
+			return
+		case <-ticker.C:
+			// The deadline runs from the first ping the client has not answered
+			if sess.server.pongTimeout > 0 && !sess.awaitingPong.Swap(true) {
+				_ = sess.conn.SetReadDeadline(time.Now().Add(sess.server.pongTimeout))
+			}
+			if err := sess.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(closeTimeout)); err != nil {
+				sess.finish()
+				return
+			}
+		}
+	}
+}
+
+// match returns the first rule matching the inbound message
+func (s *Server) match(data []byte) (rule, bool) {
+	for _, r := range s.rules {
+		if matcher.MatchMessage(data, r.matchers) {
+			return r, true
+		}
+	}
+	return rule{}, false
+}
+
+// sendAll sends the messages, each after its delay. It returns false when the
+// connection has ended.
+func (sess *session) sendAll(messages []message) bool {
+	for _, m := range messages {
+		if !sess.wait(m.delay) {
+			return false
+		}
+		if !sess.write(m.messageType, encode(template.Render(m.data, sess.request))) {
+			return false
+		}
+	}
+	return true
+}
+
+// write sends the message and injects the configured disconnects. It returns
+// false when the connection has ended.
+func (sess *session) write(messageType int, data []byte) bool {
+	sess.mu.Lock()
+	err := sess.conn.WriteMessage(messageType, data)
+	sess.sent++
+	sent := sess.sent
+	sess.mu.Unlock()
+
+	if err != nil {
+		sess.finish()
+		return false
+	}
+
+	s := sess.server
+	if (s.disconnectAfter > 0 && sent >= s.disconnectAfter) ||
+		(s.disconnectProbability > 0 && rand.Float64() < s.disconnectProbability) {
+		sess.disconnect()
+		return false
+	}
+
+	return true
+}
+
+// disconnect ends the connection. The abort mode drops the connection without
+// a close frame, like a network failure would.
+func (sess *session) disconnect() {
+	var mockLogger = logger.GetLogger()
+
+	mockLogger.InfoW("websocket disconnect injected", map[string]any{"path": sess.server.path, "mode": sess.server.disconnectMode})
+
+	if sess.server.disconnectMode == abortDisconnect {
+		sess.finish()
+		return
+	}
+	sess.closeWith(&closeFrame{code: websocket.CloseGoingAway, reason: "disconnect"})
+}
+
+// closeWith sends the close frame after its delay. The reader ends the connection
+// when the client answers it, or the close timeout does.
+func (sess *session) closeWith(f *closeFrame) {
+	if !sess.wait(f.delay) {
+		return
+	}
+
+	frame := websocket.FormatCloseMessage(f.code, f.reason)
+	if err := sess.conn.WriteControl(websocket.CloseMessage, frame, time.Now().Add(closeTimeout)); err != nil {
+		sess.finish()
+		return
+	}
+	time.AfterFunc(closeTimeout, sess.finish)
+}
+
+// wait waits the given duration, it returns false when the connection has ended
+func (sess *session) wait(d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-sess.done:
+			return false
+		default:
+			return true
+		}
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-sess.done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// finish marks the connection as ended and closes it
+func (sess *session) finish() {
+	sess.doneOnce.Do(func() {
+		close(sess.done)
+		sess.conn.Close()
+	})
+}
+
+// encode returns the payload of the message data, strings are sent as they are
+func encode(data any) []byte {
+	if s, ok := data.(string); ok {
+		return []byte(s)
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return []byte(fmt.Sprint(data))
+	}
+	return b
+}
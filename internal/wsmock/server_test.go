@@ -0,0 +1,77 @@
+package wsmock
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"mock-server/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testServer serves the websocket endpoint at /rooms/:room and returns its ws:// URL
+func testServer(t *testing.T, cfg *config.WebSocket) string {
+	t.Helper()
+
+	s, err := NewServer(config.Endpoint{Type: "websocket", Path: "/rooms/:room", WebSocket: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := gin.New()
+	engine.GET("/rooms/:room", s.Serve)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestConcurrentSessionsRenderTheirRequest(t *testing.T) {
+	url := testServer(t, &config.WebSocket{
+		Messages: []config.WebSocketMessage{{Data: "joined {{path.room}} as {{query.user}}"}},
+		Rules: []config.WebSocketRule{{
+			Matchers:  []config.Matcher{{Key: "type", Value: "whoami"}},
+			Responses: []config.WebSocketMessage{{Data: "{{header.X-Client}} in {{path.room}}", Delay: "10ms"}},
+		}},
+	})
+
+	var wg sync.WaitGroup
+	for _, client := range []struct{ room, user string }{{"red", "ann"}, {"blue", "bob"}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			header := map[string][]string{"X-Client": {client.user}}
+			conn, _, err := websocket.DefaultDialer.Dial(url+"/rooms/"+client.room+"?user="+client.user, header)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"whoami"}`)); err != nil {
+				t.Error(err)
+				return
+			}
+
+			for _, want := range []string{"joined " + client.room + " as " + client.user, client.user + " in " + client.room} {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if string(data) != want {
+					t.Errorf("message = %q, want %q", data, want)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}